@@ -0,0 +1,110 @@
+package budget
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Jawkx/ctxcat/internal/tokenizer"
+)
+
+// Document is a formatted file that is a candidate for the output.
+type Document struct {
+	Path      string
+	Content   string // Raw file content
+	Output    string // Content after the template has been applied
+	Tokens    int    // Token count of Output
+	Truncated bool
+}
+
+// Budget describes the token limit and how to re-measure documents.
+type Budget struct {
+	MaxTokens int
+	Tokenizer tokenizer.Tokenizer
+
+	// Render re-applies the template after a strategy has changed a document's content.
+	Render func(path, content string) (string, error)
+
+	// Priority holds glob patterns for files that should be kept before all others.
+	Priority []string
+}
+
+// Strategy decides which documents to keep when they do not all fit in the budget.
+type Strategy interface {
+	// Name returns the identifier used to select the strategy with --fit-strategy.
+	Name() string
+	// Fit returns the documents to keep and the documents that were dropped.
+	// Kept documents must remain in their original relative order.
+	Fit(docs []*Document, b *Budget) (kept, omitted []*Document)
+}
+
+// DefaultStrategy keeps files in output order until the budget runs out.
+const DefaultStrategy = "ordered"
+
+var strategies = map[string]Strategy{}
+
+// Register makes a strategy available by name. It panics if the name is taken.
+func Register(s Strategy) {
+	if _, exists := strategies[s.Name()]; exists {
+		panic(fmt.Sprintf("budget: strategy %q registered twice", s.Name()))
+	}
+	strategies[s.Name()] = s
+}
+
+// Lookup returns the strategy registered under name.
+func Lookup(name string) (Strategy, error) {
+	if s, ok := strategies[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown fit strategy %q (expected one of: %s)", name, strings.Join(Names(), ", "))
+}
+
+// Names lists the registered strategies in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fit applies the strategy if the documents exceed the budget.
+// A MaxTokens of zero or less means the budget is unlimited.
+func (b *Budget) Fit(s Strategy, docs []*Document) (kept, omitted []*Document) {
+	if b.MaxTokens <= 0 || totalTokens(docs) <= b.MaxTokens {
+		return docs, nil
+	}
+	return s.Fit(docs, b)
+}
+
+func totalTokens(docs []*Document) int {
+	total := 0
+	for _, doc := range docs {
+		total += doc.Tokens
+	}
+	return total
+}
+
+// keepByRank greedily admits documents in the order given by rank and then
+// restores the original order for the kept set.
+func keepByRank(docs []*Document, rank []*Document, maxTokens int) (kept, omitted []*Document) {
+	admitted := make(map[*Document]bool, len(docs))
+	used := 0
+	for _, doc := range rank {
+		if used+doc.Tokens > maxTokens {
+			continue
+		}
+		used += doc.Tokens
+		admitted[doc] = true
+	}
+
+	for _, doc := range docs {
+		if admitted[doc] {
+			kept = append(kept, doc)
+		} else {
+			omitted = append(omitted, doc)
+		}
+	}
+	return kept, omitted
+}
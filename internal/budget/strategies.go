@@ -0,0 +1,166 @@
+package budget
+
+import (
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Jawkx/ctxcat/internal/processor"
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+func init() {
+	Register(orderedStrategy{})
+	Register(largestStrategy{})
+	Register(deepestStrategy{})
+	Register(priorityStrategy{})
+	Register(truncateStrategy{})
+}
+
+// orderedStrategy keeps files in output order, skipping any that no longer fit.
+type orderedStrategy struct{}
+
+func (orderedStrategy) Name() string { return DefaultStrategy }
+
+func (orderedStrategy) Fit(docs []*Document, b *Budget) ([]*Document, []*Document) {
+	return keepByRank(docs, docs, b.MaxTokens)
+}
+
+// largestStrategy drops the largest files first.
+type largestStrategy struct{}
+
+func (largestStrategy) Name() string { return "largest" }
+
+func (largestStrategy) Fit(docs []*Document, b *Budget) ([]*Document, []*Document) {
+	rank := append([]*Document(nil), docs...)
+	sort.SliceStable(rank, func(i, j int) bool {
+		return rank[i].Tokens < rank[j].Tokens
+	})
+	return keepByRank(docs, rank, b.MaxTokens)
+}
+
+// deepestStrategy drops the most deeply nested files first.
+type deepestStrategy struct{}
+
+func (deepestStrategy) Name() string { return "deepest" }
+
+func (deepestStrategy) Fit(docs []*Document, b *Budget) ([]*Document, []*Document) {
+	rank := append([]*Document(nil), docs...)
+	sort.SliceStable(rank, func(i, j int) bool {
+		return depth(rank[i].Path) < depth(rank[j].Path)
+	})
+	return keepByRank(docs, rank, b.MaxTokens)
+}
+
+func depth(path string) int {
+	return strings.Count(filepath.ToSlash(filepath.Clean(path)), "/")
+}
+
+// priorityStrategy keeps files matching the --priority globs before any others.
+type priorityStrategy struct{}
+
+func (priorityStrategy) Name() string { return "priority" }
+
+func (priorityStrategy) Fit(docs []*Document, b *Budget) ([]*Document, []*Document) {
+	rank := append([]*Document(nil), docs...)
+	sort.SliceStable(rank, func(i, j int) bool {
+		return b.isPriority(rank[i].Path) && !b.isPriority(rank[j].Path)
+	})
+	return keepByRank(docs, rank, b.MaxTokens)
+}
+
+func (b *Budget) isPriority(path string) bool {
+	for _, pattern := range b.Priority {
+		match, err := doublestar.PathMatch(filepath.ToSlash(pattern), filepath.ToSlash(path))
+		if err == nil && match {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateStrategy shortens every file by the same proportion so that all of them fit.
+type truncateStrategy struct{}
+
+func (truncateStrategy) Name() string { return "truncate" }
+
+func (truncateStrategy) Fit(docs []*Document, b *Budget) ([]*Document, []*Document) {
+	// Only the content shrinks: the template around it is rendered for every file
+	// whatever its length.
+	overheads := make([]int, len(docs))
+	available, content := b.MaxTokens, 0
+	for i, doc := range docs {
+		overheads[i] = min(b.overhead(doc), doc.Tokens)
+		available -= overheads[i]
+		content += doc.Tokens - overheads[i]
+	}
+	ratio := 0.0
+	if available > 0 && content > 0 {
+		ratio = float64(available) / float64(content)
+	}
+	for i, doc := range docs {
+		target := overheads[i] + int(float64(doc.Tokens-overheads[i])*ratio)
+		b.truncate(doc, target)
+	}
+	// Rounding and the omitted lines markers can still leave us slightly over budget.
+	return keepByRank(docs, docs, b.MaxTokens)
+}
+
+// overhead returns the tokens of doc's rendered output that are not its content.
+func (b *Budget) overhead(doc *Document) int {
+	output, err := b.Render(doc.Path, "")
+	if err != nil {
+		return 0
+	}
+	return b.Tokenizer.Count(output)
+}
+
+// truncate cuts doc's content at a line boundary so that its rendered output
+// is at most target tokens.
+func (b *Budget) truncate(doc *Document, target int) {
+	if doc.Tokens <= target {
+		return
+	}
+
+	measure := func(n int) int {
+		output, err := b.Render(doc.Path, truncatedContent(doc.Content, n))
+		if err != nil {
+			return math.MaxInt
+		}
+		return b.Tokenizer.Count(output)
+	}
+
+	// Binary search for the longest prefix that fits.
+	lo, hi := 0, len(doc.Content)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if measure(mid) <= target {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	content := truncatedContent(doc.Content, lo)
+	output, err := b.Render(doc.Path, content)
+	if err != nil {
+		return
+	}
+	doc.Content = content
+	doc.Output = output
+	doc.Tokens = b.Tokenizer.Count(doc.Output)
+	doc.Truncated = true
+}
+
+// truncatedContent keeps the lines of content that end before cut and replaces
+// the rest with the marker for omitted lines.
+func truncatedContent(content string, cut int) string {
+	keep := strings.LastIndexByte(content[:cut], '\n') + 1
+	rest := content[keep:]
+	n := strings.Count(rest, "\n")
+	if rest != "" && !strings.HasSuffix(rest, "\n") {
+		n++
+	}
+	return content[:keep] + processor.OmittedMarker(n)
+}
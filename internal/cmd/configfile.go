@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Jawkx/ctxcat/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	profileName string
+	noConfig    bool
+
+	// defaultPaths are the input paths from the configuration, used when none are given.
+	defaultPaths []string
+)
+
+// applyConfigFile sets every flag that was not given on the command line from the
+// configuration files and the selected profile.
+// Precedence: flag > profile > repository > user > default.
+func applyConfigFile(cmd *cobra.Command) error {
+	if noConfig {
+		if profileName != "" {
+			return fmt.Errorf("--profile cannot be used with --no-config")
+		}
+		return nil
+	}
+
+	file, err := config.LoadFile(".")
+	if err != nil {
+		return err
+	}
+	settings, err := file.Resolve(profileName)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := settings[key]
+		if key == "paths" {
+			paths, err := toStrings(value)
+			if err != nil {
+				return fmt.Errorf("config option paths: %w", err)
+			}
+			defaultPaths = paths
+			continue
+		}
+
+		flag := cmd.Flags().Lookup(key)
+		if flag == nil {
+			// Options for the main command are allowed in the config but ignored by subcommands.
+			if cmd.Root().Flags().Lookup(key) != nil {
+				continue
+			}
+			return fmt.Errorf("unknown config option %q", key)
+		}
+		if flag.Changed {
+			continue
+		}
+		if err := setFlag(flag, value); err != nil {
+			return fmt.Errorf("config option %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// setFlag assigns a configuration value to a flag. Lists replace the flag's
+// default rather than appending to it.
+func setFlag(flag *pflag.Flag, value any) error {
+	if sliceValue, ok := flag.Value.(pflag.SliceValue); ok {
+		values, err := toStrings(value)
+		if err != nil {
+			return err
+		}
+		return sliceValue.Replace(values)
+	}
+
+	switch value.(type) {
+	case []any, map[string]any:
+		return fmt.Errorf("expected a single value")
+	}
+	return flag.Value.Set(fmt.Sprint(value))
+}
+
+// toStrings converts a scalar or a list from the configuration into strings.
+func toStrings(value any) ([]string, error) {
+	switch v := value.(type) {
+	case []any:
+		values := make([]string, len(v))
+		for i, item := range v {
+			switch item.(type) {
+			case []any, map[string]any:
+				return nil, fmt.Errorf("expected a list of values")
+			}
+			values[i] = fmt.Sprint(item)
+		}
+		return values, nil
+	case map[string]any:
+		return nil, fmt.Errorf("expected a list of values")
+	case nil:
+		return nil, nil
+	default:
+		return []string{fmt.Sprint(v)}, nil
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Jawkx/ctxcat/internal/processor"
+)
+
+var (
+	maxFileSize string
+	minFileSize string
+	maxLines    int
+	newerThan   string
+	olderThan   string
+	grep        string
+	grepInvert  string
+	grepContext int
+)
+
+// sizeUnits maps the suffixes accepted by parseSize to their multiplier.
+var sizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+}
+
+// timeLayouts are the date formats accepted by parseTime.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// applyFileFilters parses the size, line, modification time and content flags into config.
+func applyFileFilters(config *processor.Config) error {
+	var err error
+	if config.MaxFileSize, err = parseSize(maxFileSize); err != nil {
+		return fmt.Errorf("invalid --max-file-size: %w", err)
+	}
+	if config.MinFileSize, err = parseSize(minFileSize); err != nil {
+		return fmt.Errorf("invalid --min-file-size: %w", err)
+	}
+	config.MaxLines = maxLines
+
+	now := time.Now()
+	if config.NewerThan, err = parseTime(newerThan, now); err != nil {
+		return fmt.Errorf("invalid --newer-than: %w", err)
+	}
+	if config.OlderThan, err = parseTime(olderThan, now); err != nil {
+		return fmt.Errorf("invalid --older-than: %w", err)
+	}
+
+	if config.Grep, err = compileGrep(grep); err != nil {
+		return fmt.Errorf("invalid --grep: %w", err)
+	}
+	if config.GrepInvert, err = compileGrep(grepInvert); err != nil {
+		return fmt.Errorf("invalid --grep-v: %w", err)
+	}
+	if grepContext >= 0 && grep == "" {
+		return fmt.Errorf("--grep-context requires --grep")
+	}
+	return nil
+}
+
+// compileGrep compiles a --grep pattern. An empty pattern is nil.
+func compileGrep(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// parseSize parses a size such as "512", "100KB" or "1.5M". Units are powers of 1024.
+// An empty string is zero.
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	i := strings.LastIndexAny(s, "0123456789.") + 1
+	// "KiB" and friends are accepted as spellings of "KB".
+	unit, ok := sizeUnits[strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s[i:])), "ib")]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit in %q (expected B, KB, MB or GB)", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// parseTime parses a point in time given as a duration before now ("36h", "7d", "2w"),
+// a date ("2024-05-01", optionally with a time) or the path of a file whose
+// modification time is used. An empty string is the zero time.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := parseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if info, err := os.Stat(s); err == nil {
+		return info.ModTime(), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration, a date or an existing file", s)
+}
+
+// parseDuration is time.ParseDuration with additional "d" (day) and "w" (week) units.
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Jawkx/ctxcat/internal/processor"
+	"github.com/Jawkx/ctxcat/internal/tokenizer"
+	"github.com/spf13/cobra"
+)
+
+var explain bool
+
+var lsCmd = &cobra.Command{
+	Use:   "ls [OPTIONS] [PATH...]",
+	Short: "Lists the files that would be included, without their contents.",
+	Long: "Lists the files that would be included with their size, token estimate and line count.\n" +
+		"With --explain, also lists every rejected path and the rule that rejected it.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		proc, files, _, err := selectFiles(args)
+		if err != nil {
+			return err
+		}
+
+		tok, err := tokenizer.New(tokenizerName)
+		if err != nil {
+			return fmt.Errorf("could not load tokenizer: %w", err)
+		}
+
+		out := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(out, "SIZE\tTOKENS\tLINES\t  PATH")
+
+		var totalSize, totalTokens, totalLines int
+		for _, file := range files {
+			content, err := proc.ReadFile(file)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error processing file %s: %v\n", file, err)
+				continue
+			}
+			f := processor.NewFile(0, file, string(content))
+			if ranges := proc.Ranges(file); ranges != nil {
+				if err := processor.SelectRanges(f, ranges); err != nil {
+					fmt.Fprintf(os.Stderr, "Error processing file %s: %v\n", file, err)
+					continue
+				}
+			}
+			size, tokens, lines := f.Size, tok.Count(f.Content), processor.CountLines([]byte(f.Content))
+			totalSize += size
+			totalTokens += tokens
+			totalLines += lines
+			fmt.Fprintf(out, "%d\t%d\t%d\t  %s\n", size, tokens, lines, file)
+		}
+		fmt.Fprintf(out, "%d\t%d\t%d\t  total (%d file(s))\n", totalSize, totalTokens, totalLines, len(files))
+		if err := out.Flush(); err != nil {
+			return err
+		}
+
+		if !explain {
+			return nil
+		}
+
+		rejected := proc.Rejected()
+		fmt.Fprintf(cmd.OutOrStdout(), "\nRejected (%d):\n", len(rejected))
+		out = tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		for _, r := range rejected {
+			path := r.Path
+			if r.IsDir {
+				path += "/"
+			}
+			fmt.Fprintf(out, "  %s\t%s\t%s\n", path, r.Rule, r.Detail)
+		}
+		return out.Flush()
+	},
+}
+
+func init() {
+	lsCmd.Flags().
+		BoolVar(&explain, "explain", false, "Also list rejected paths and the rule that rejected each one.")
+	rootCmd.AddCommand(lsCmd)
+}
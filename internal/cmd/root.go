@@ -3,12 +3,21 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"github.com/Jawkx/ctxcat/internal/budget"
 	"github.com/Jawkx/ctxcat/internal/config"
+	"github.com/Jawkx/ctxcat/internal/gitutil"
+	"github.com/Jawkx/ctxcat/internal/gomod"
 	"github.com/Jawkx/ctxcat/internal/processor"
+	"github.com/Jawkx/ctxcat/internal/tokenizer"
+	"github.com/Jawkx/ctxcat/internal/tree"
 	"github.com/Jawkx/ctxcat/internal/walker"
 	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -16,11 +25,52 @@ import (
 var (
 	noRecursive     bool
 	excludePatterns []string
+	includePatterns []string
+	extensions      []string
 	noGitignore     bool
 	ignoreFiles     []string
 	noBinaryCheck   bool
+	noDefaultIgnore bool
 	outputFile      string
 	template        string
+	format          string
+	goTemplate      bool
+	header          string
+	footer          string
+	showTree        bool
+	treeIgnored     bool
+	maxTokens       int
+	tokenizerName   string
+	fitStrategy     string
+	priorityGlobs   []string
+	changedSince    string
+	stagedOnly      bool
+	unstagedOnly    bool
+	withDiff        bool
+	revision        string
+	walkerName      string
+	diffOnly        bool
+	maxLinesPerFile int
+	maxBytesPerFile string
+	truncateMode    string
+	lineNumbers     bool
+	stripComments   bool
+	outline         bool
+	outlineGlobs    []string
+	fullGlobs       []string
+	modeFlags       []string
+	followImports   int
+	followVendor    bool
+	followModCache  bool
+	followMode      string
+	callersOf       []string
+	callersScope    string
+	collapseBlanks  bool
+	lineNumberFmt   string
+	lineNumberWidth int
+	noRedact        bool
+	redactPatterns  []string
+	allowSecrets    bool
 	showVersion     bool
 )
 
@@ -34,40 +84,61 @@ var rootCmd = &cobra.Command{
 	Use:     "ctxcat [OPTIONS] [PATH...]",
 	Short:   "Gathers file contents for LLM prompts.",
 	Version: version,
+	// Paths are positional arguments, not subcommands.
+	Args: cobra.ArbitraryArgs,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return applyConfigFile(cmd)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		// 1. Get input paths from arguments or stdin
-		paths, err := walker.GetInputPaths(args)
+		// 1-4. Select the files to include
+		proc, files, dependencies, err := selectFiles(args)
 		if err != nil {
-			return fmt.Errorf("could not get input paths: %w", err)
+			return err
 		}
-
-		// 2. Configure the file processor
-		proc, err := processor.New(&processor.Config{
-			NoRecursive:   noRecursive,
-			NoGitignore:   noGitignore,
-			IgnoreFiles:   ignoreFiles,
-			ExcludeGlobs:  excludePatterns,
-			NoBinaryCheck: noBinaryCheck,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to configure file processor: %w", err)
+		if followMode != "" && !slices.Contains(processor.Modes, followMode) {
+			return fmt.Errorf("unknown --follow-imports-mode %q (expected one of: %s)", followMode, strings.Join(processor.Modes, ", "))
 		}
-
-		// 3. Process paths to get final list of files
-		files, err := proc.ProcessPaths(paths)
-		if err != nil {
-			return fmt.Errorf("error processing paths: %w", err)
+		if lineNumberWidth < 0 {
+			return fmt.Errorf("invalid --line-number-width %d (expected 0 or more)", lineNumberWidth)
+		}
+		isDependency := make(map[string]bool, len(dependencies))
+		for _, file := range dependencies {
+			isDependency[file] = true
 		}
-
-		// 4. Sort files for deterministic output
-		sort.Strings(files)
 
 		// 5. Load the output template
+		if format != "" && template != "" {
+			return fmt.Errorf("--format and --template cannot be used together")
+		}
+		// Text around JSON output would make it invalid.
+		jsonOutput := format == "json" || format == "jsonl"
+		if showTree && jsonOutput {
+			return fmt.Errorf("--tree cannot be used with --format %s", format)
+		}
+		if (header != "" || footer != "") && jsonOutput {
+			return fmt.Errorf("--header and --footer cannot be used with --format %s", format)
+		}
 		finalTemplate, err := config.LoadTemplate(template)
 		if err != nil {
 			return fmt.Errorf("could not load template: %w", err)
 		}
+		if (withDiff || diffOnly) && finalTemplate == config.DefaultTemplate {
+			finalTemplate = config.DiffTemplate
+			if diffOnly {
+				finalTemplate = config.DiffOnlyTemplate
+			}
+		}
+		// Header and footer files are not applied to JSON output.
+		var finalHeader, finalFooter string
+		if !jsonOutput {
+			if finalHeader, err = config.LoadHeader(header); err != nil {
+				return fmt.Errorf("could not load header: %w", err)
+			}
+			if finalFooter, err = config.LoadFooter(footer); err != nil {
+				return fmt.Errorf("could not load footer: %w", err)
+			}
+		}
 
 		// 6. Set up the output writer
 		var out io.Writer = os.Stdout
@@ -82,27 +153,245 @@ var rootCmd = &cobra.Command{
 		writer := bufio.NewWriter(out)
 		defer writer.Flush()
 
-		// 7. Format and write each file
-		formatter, err := processor.NewFormatter(finalTemplate)
+		// 7. Format each file and fit the result into the token budget
+		tok, err := tokenizer.New(tokenizerName)
 		if err != nil {
-			return fmt.Errorf("failed to create formatter: %w", err)
+			return fmt.Errorf("could not load tokenizer: %w", err)
 		}
 
-		for i, file := range files {
-			formattedOutput, err := formatter.Format(file)
+		renderer, err := processor.NewRenderer(format, &processor.FormatterConfig{
+			Template:   finalTemplate,
+			GoTemplate: goTemplate,
+			Tokenizer:  tok,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create renderer: %w", err)
+		}
+		truncation, err := newTruncation()
+		if err != nil {
+			return err
+		}
+		// Files in the truncated mode are truncated even when no per-file limit is set.
+		modeTruncation := truncation
+		if modeTruncation == nil {
+			modeTruncation = &processor.Truncation{MaxLines: processor.DefaultTruncateLines, Mode: truncateMode}
+		}
+		modes, err := modeRules()
+		if err != nil {
+			return err
+		}
+		numbers := &processor.LineNumbers{Format: lineNumberFmt, Width: lineNumberWidth}
+		// Files are only numbered when the numbers are part of the output.
+		numberLines := lineNumbers || strings.Contains(finalTemplate, "{numbered_content}") ||
+			strings.Contains(finalTemplate, ".NumberedContent")
+		redactor, err := processor.NewRedactor(!noRedact, redactPatterns)
+		if err != nil {
+			return err
+		}
+
+		// prepared holds each file after its content has been transformed. Fit strategies
+		// only see the content, so the file is looked up again to render it.
+		prepared := make(map[string]*processor.File)
+		newFile := func(index int, path, content string) *processor.File {
+			file := *prepared[path]
+			file.Index = index
+			if content != file.Content {
+				// A fit strategy kept the first lines of the content and replaced the
+				// rest with an omitted lines marker.
+				switch {
+				case lineNumbers:
+					file.NumberedContent = content
+				case numberLines:
+					shortened := file
+					shortened.SetLines(processor.HeadLines(file.Lines(), strings.Count(content, "\n")))
+					file.NumberedContent = numbers.Number(&shortened)
+				}
+				file.SetContent(content)
+				file.Truncated = true
+			}
+			return &file
+		}
+		render := func(path, content string) (string, error) {
+			return renderer.Render(newFile(0, path, content))
+		}
+
+		strategy, err := budget.Lookup(fitStrategy)
+		if err != nil {
+			return err
+		}
+
+		// transform applies the content transforms selected by the flags to a file.
+		transform := func(file string, f *processor.File) error {
+			// Symbols are found in the content as read.
+			ranges, err := processor.ResolveSymbols(f, proc.Ranges(file))
+			if err != nil {
+				return err
+			}
+			// Secrets are redacted first, in the whole file, so that no later transform
+			// can reveal them. Redacted lines keep their numbers for the line ranges.
+			redacted := make(map[string]int)
+			redactor.Apply(f, redacted)
+			if ranges != nil {
+				if err := processor.SelectRanges(f, ranges); err != nil {
+					return err
+				}
+			}
+			// A selected line range is already the part of the file that was asked for.
+			if f.Mode == processor.ModeOutline && proc.Ranges(file) == nil {
+				if _, err := processor.Outline(f); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: could not outline %s, including it in full: %v\n", file, err)
+				}
+			}
+			if stripComments {
+				processor.StripComments(f)
+			}
+			if collapseBlanks {
+				processor.CollapseBlankLines(f)
+			}
+			if grepContext >= 0 {
+				f.SetLines(processor.GrepContext(f.Lines(), proc.Config().Grep, grepContext))
+			}
+			switch {
+			case f.Mode == processor.ModeTruncated:
+				modeTruncation.Apply(f)
+			case f.Mode != processor.ModeFull && truncation != nil:
+				truncation.Apply(f)
+			}
+			if numberLines {
+				f.NumberedContent = numbers.Number(f)
+			}
+			if lineNumbers {
+				f.SetContent(f.NumberedContent)
+			}
+			if withDiff || diffOnly {
+				diff, err := gitutil.FileDiff(file, diffSelector(true))
+				if err != nil {
+					return err
+				}
+				f.Diff = redactor.Redact(file, diff, redacted)
+				if diffOnly {
+					f.SetContent("")
+				}
+			}
+			if len(redacted) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: redacted secrets in %s: %s\n", file, processor.FormatCounts(redacted))
+			}
+			return nil
+		}
+
+		var docs []*budget.Document
+		for _, file := range files {
+			contentBytes, err := proc.ReadFile(file)
 			if err != nil {
 				// Log error to stderr and continue with other files
 				fmt.Fprintf(os.Stderr, "Error processing file %s: %v\n", file, err)
 				continue
 			}
-			if _, err := writer.WriteString(formattedOutput); err != nil {
-				return fmt.Errorf("failed to write to output: %w", err)
+			f := processor.NewFile(0, file, string(contentBytes))
+			f.Mode = modes.Mode(file)
+			if f.Mode == "" && isDependency[file] {
+				f.Mode = followMode
 			}
-			// Add a newline between files if the template doesn't end with one
-			// and it's not the last file.
-			if len(formattedOutput) > 0 && formattedOutput[len(formattedOutput)-1] != '\n' &&
-				i < len(files)-1 {
-				writer.WriteRune('\n')
+			if f.Mode == processor.ModePathOnly {
+				// Only the file's metadata is rendered.
+				f.SetContent("")
+			} else if err := transform(file, f); err != nil {
+				fmt.Fprintf(os.Stderr, "Error processing file %s: %v\n", file, err)
+				continue
+			}
+			formattedOutput, err := renderer.Render(f)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error processing file %s: %v\n", file, err)
+				continue
+			}
+			prepared[file] = f
+			doc := &budget.Document{Path: file, Content: f.Content, Output: formattedOutput}
+			if maxTokens > 0 {
+				doc.Tokens = tok.Count(formattedOutput)
+			}
+			docs = append(docs, doc)
+		}
+
+		// The header and footer share the budget with the files.
+		var rejected []processor.Rejection
+		if treeIgnored {
+			rejected = proc.Rejected()
+		}
+
+		available := maxTokens
+		if maxTokens > 0 {
+			summary := summarize(docs, rejected, "", tok)
+			for _, doc := range docs {
+				summary.TotalTokens += doc.Tokens
+			}
+			texts := []string{finalHeader, finalFooter}
+			if showTree {
+				texts = append(texts, summary.Tree)
+			}
+			for _, text := range texts {
+				rendered, err := processor.RenderSummary(text, goTemplate, tok, summary)
+				if err != nil {
+					return fmt.Errorf("could not render header or footer: %w", err)
+				}
+				available -= tok.Count(rendered)
+			}
+		}
+
+		b := &budget.Budget{
+			MaxTokens: available,
+			Tokenizer: tok,
+			Render:    render,
+			Priority:  priorityGlobs,
+		}
+		var kept, omitted []*budget.Document
+		if maxTokens > 0 && available <= 0 {
+			// A budget of zero would be unlimited, but no file fits.
+			fmt.Fprintf(os.Stderr, "Warning: the header, footer and tree alone use the token budget of %d\n", maxTokens)
+			omitted = docs
+		} else {
+			kept, omitted = b.Fit(strategy, docs)
+		}
+
+		for _, doc := range kept {
+			if doc.Truncated {
+				fmt.Fprintf(os.Stderr, "Warning: truncated %s to fit token budget\n", doc.Path)
+			}
+		}
+		if len(omitted) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: token budget of %d exceeded, omitted %d file(s):\n", maxTokens, len(omitted))
+			for _, doc := range omitted {
+				fmt.Fprintf(os.Stderr, "  %s (%d tokens)\n", doc.Path, doc.Tokens)
+			}
+		}
+
+		// 8. Render the final output, numbering the files that were kept
+		records := make([]string, 0, len(kept))
+		for i, doc := range kept {
+			record, err := renderer.Render(newFile(i+1, doc.Path, doc.Content))
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		body := renderer.Join(records)
+
+		summary := summarize(kept, rejected, body, tok)
+		renderedHeader, err := processor.RenderSummary(finalHeader, goTemplate, tok, summary)
+		if err != nil {
+			return fmt.Errorf("could not render header: %w", err)
+		}
+		renderedFooter, err := processor.RenderSummary(finalFooter, goTemplate, tok, summary)
+		if err != nil {
+			return fmt.Errorf("could not render footer: %w", err)
+		}
+
+		parts := []string{renderedHeader, body, renderedFooter}
+		if showTree {
+			parts = []string{renderedHeader, summary.Tree + "\n", body, renderedFooter}
+		}
+		for _, part := range parts {
+			if _, err := writer.WriteString(part); err != nil {
+				return fmt.Errorf("failed to write to output: %w", err)
 			}
 		}
 
@@ -110,6 +399,249 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// selectFiles resolves the input paths and filters them into a sorted list of files.
+// With --follow-imports, the files of imported Go packages are added, and also
+// returned separately as dependencies. With --callers-of, the Go files that refer
+// to the given symbols are added.
+func selectFiles(args []string) (*processor.FileProcessor, []string, []string, error) {
+	// 1. Get input paths from arguments or stdin
+	paths, err := walker.GetInputPaths(args, defaultPaths)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not get input paths: %w", err)
+	}
+
+	// 2. Configure the file processor
+	var fsys processor.FileSystem
+	if revision != "" {
+		if !diffSelector(false).IsZero() || withDiff || diffOnly {
+			return nil, nil, nil, fmt.Errorf("--rev cannot be combined with git change selectors or diffs")
+		}
+		if followImports > 0 {
+			return nil, nil, nil, fmt.Errorf("--rev cannot be combined with --follow-imports")
+		}
+		if len(callersOf) > 0 {
+			return nil, nil, nil, fmt.Errorf("--rev cannot be combined with --callers-of")
+		}
+		revFS, err := gitutil.NewRevFS(".", revision)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not read revision %s: %w", revision, err)
+		}
+		fsys = revFS
+	}
+	var lister processor.FileLister
+	switch walkerName {
+	case "auto":
+		// Git's own ignore handling only applies to the working tree.
+		if !noGitignore && revision == "" {
+			lister = gitutil.NewLsFiles()
+		}
+	case "fs":
+	default:
+		return nil, nil, nil, fmt.Errorf("unknown walker %q (expected auto or fs)", walkerName)
+	}
+	procConfig := &processor.Config{
+		NoRecursive:      noRecursive,
+		NoGitignore:      noGitignore,
+		IgnoreFiles:      ignoreFiles,
+		ExcludeGlobs:     excludePatterns,
+		IncludeGlobs:     includePatterns,
+		Extensions:       extensions,
+		NoBinaryCheck:    noBinaryCheck,
+		NoDefaultIgnores: noDefaultIgnore,
+		AllowSecretFiles: allowSecrets,
+		FS:               fsys,
+		Lister:           lister,
+	}
+	if err := applyFileFilters(procConfig); err != nil {
+		return nil, nil, nil, err
+	}
+	proc, err := processor.New(procConfig)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to configure file processor: %w", err)
+	}
+
+	// 3. Process paths to get final list of files
+	files, err := proc.ProcessPaths(paths)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error processing paths: %w", err)
+	}
+
+	// Restrict the selection to files changed in git
+	if selector := diffSelector(false); !selector.IsZero() {
+		changed, err := gitutil.ChangedFiles(".", selector)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not list changed files: %w", err)
+		}
+		isChanged := make(map[string]bool, len(changed))
+		for _, path := range changed {
+			isChanged[filepath.Clean(path)] = true
+		}
+		filtered := files[:0]
+		for _, file := range files {
+			if isChanged[filepath.Clean(file)] {
+				filtered = append(filtered, file)
+			}
+		}
+		files = filtered
+	}
+
+	// Add the packages imported by the selected Go files
+	var dependencies []string
+	if followImports > 0 {
+		resolver := &gomod.Resolver{Vendor: followVendor, ModCache: followModCache}
+		imported, err := resolver.Follow(files, followImports)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not follow imports: %w", err)
+		}
+		dependencies = proc.FilterFiles(imported)
+		files = append(files, dependencies...)
+	}
+
+	// Add the files that refer to the symbols given with --callers-of
+	if len(callersOf) > 0 {
+		callers, err := findCallers(proc, files)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		files = append(files, callers...)
+	}
+
+	// 4. Sort files for deterministic output
+	sort.Strings(files)
+
+	return proc, files, dependencies, nil
+}
+
+// findCallers returns the files of the Go module in the working directory that refer
+// to the symbols given with --callers-of, leaving out those already in files. With
+// --callers-scope function, only the declarations that refer to the symbols are
+// selected from the added files, and from files already selected with a range.
+func findCallers(proc *processor.FileProcessor, files []string) ([]string, error) {
+	if callersScope != "file" && callersScope != "function" {
+		return nil, fmt.Errorf("unknown --callers-scope %q (expected file or function)", callersScope)
+	}
+	m, err := gomod.FindModule(".")
+	if err != nil {
+		return nil, fmt.Errorf("could not find callers: %w", err)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("--callers-of requires a Go module, but no go.mod was found")
+	}
+
+	selected := make(map[string]bool, len(files))
+	for _, file := range files {
+		selected[filepath.Clean(file)] = true
+	}
+	var callers []string
+	ranges := make(map[string][]processor.LineRange)
+	for _, s := range callersOf {
+		sym, err := gomod.ParseSymbol(s)
+		if err != nil {
+			return nil, err
+		}
+		references, err := gomod.FindReferences(m, sym)
+		if err != nil {
+			return nil, fmt.Errorf("could not find callers of %s: %w", sym, err)
+		}
+		if len(references) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: no references to %s found\n", sym)
+		}
+		for _, ref := range references {
+			if selected[filepath.Clean(ref.File)] && proc.Ranges(ref.File) == nil {
+				continue
+			}
+			if _, ok := ranges[ref.File]; !ok && !selected[filepath.Clean(ref.File)] {
+				callers = append(callers, ref.File)
+			}
+			ranges[ref.File] = append(ranges[ref.File], processor.LineRange{Start: ref.Start, End: ref.End})
+		}
+	}
+
+	callers = proc.FilterFiles(callers)
+	if callersScope == "function" {
+		for file, fileRanges := range ranges {
+			proc.AddRanges(file, fileRanges)
+		}
+	}
+	return callers, nil
+}
+
+// diffSelector returns the git changes selected by the command-line flags.
+// When forDiff is set and no selector was given, it selects all uncommitted changes.
+func diffSelector(forDiff bool) gitutil.DiffSelector {
+	selector := gitutil.DiffSelector{
+		Since:    changedSince,
+		Staged:   stagedOnly,
+		Unstaged: unstagedOnly,
+	}
+	if forDiff && selector.IsZero() {
+		selector.Staged, selector.Unstaged = true, true
+	}
+	return selector
+}
+
+// newTruncation returns the per-file truncation selected by the command-line flags,
+// or nil if files are not truncated.
+func newTruncation() (*processor.Truncation, error) {
+	maxBytes, err := parseSize(maxBytesPerFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --max-bytes-per-file: %w", err)
+	}
+	truncation, err := processor.NewTruncation(maxLinesPerFile, int(maxBytes), truncateMode)
+	if err != nil {
+		return nil, err
+	}
+	if maxLinesPerFile == 0 && maxBytes == 0 {
+		return nil, nil
+	}
+	return truncation, nil
+}
+
+// modeRules returns the rendering mode rules given with --mode, followed by the
+// rules implied by --full-glob, --outline-glob and --outline, in that order.
+func modeRules() (processor.ModeRules, error) {
+	var rules processor.ModeRules
+	for _, s := range modeFlags {
+		rule, err := processor.ParseModeRule(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --mode: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	for _, glob := range fullGlobs {
+		rules = append(rules, processor.ModeRule{Glob: glob, Mode: processor.ModeFull})
+	}
+	for _, glob := range outlineGlobs {
+		rules = append(rules, processor.ModeRule{Glob: glob, Mode: processor.ModeOutline})
+	}
+	if outline {
+		rules = append(rules, processor.ModeRule{Glob: "**", Mode: processor.ModeOutline})
+	}
+	return rules, nil
+}
+
+// summarize collects the aggregate values for the header and footer templates.
+// Rejected paths are included in the tree, marked with the rule that rejected them.
+func summarize(docs []*budget.Document, rejected []processor.Rejection, body string, tok tokenizer.Tokenizer) *processor.Summary {
+	entries := make([]tree.Entry, 0, len(docs)+len(rejected))
+	totalBytes := 0
+	for _, doc := range docs {
+		entries = append(entries, tree.Entry{Path: doc.Path})
+		totalBytes += len(doc.Content)
+	}
+	for _, r := range rejected {
+		entries = append(entries, tree.Entry{Path: r.Path, IsDir: r.IsDir, Note: r.Rule})
+	}
+	return &processor.Summary{
+		FileCount:   len(docs),
+		TotalBytes:  totalBytes,
+		TotalTokens: tok.Count(body),
+		Date:        time.Now().Format("2006-01-02"),
+		GitBranch:   gitutil.CurrentBranch("."),
+		Tree:        tree.RenderEntries(entries),
+	}
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		// Cobra already prints the error, so we just exit
@@ -123,19 +655,122 @@ func init() {
 
 	rootCmd.SetVersionTemplate(`{{printf "%s\n" .Version}}`)
 
-	rootCmd.Flags().
+	rootCmd.PersistentFlags().
 		BoolVarP(&noRecursive, "no-recursive", "r", false, "Disables recursive traversal of directories.")
-	rootCmd.Flags().
+	rootCmd.PersistentFlags().
 		StringSliceVarP(&excludePatterns, "exclude", "e", nil, "A glob pattern for files or directories to exclude. Can be specified multiple times.")
-	rootCmd.Flags().
+	rootCmd.PersistentFlags().
+		StringSliceVarP(&includePatterns, "include", "i", nil, "A glob pattern for files to include; other files are skipped. Can be specified multiple times.")
+	rootCmd.PersistentFlags().
+		StringSliceVar(&extensions, "ext", nil, "Comma-separated file extensions to include, e.g. go,sql. Combined with --include.")
+	rootCmd.PersistentFlags().
 		BoolVar(&noGitignore, "no-gitignore", false, "Do not respect the rules found in .gitignore files.")
-	rootCmd.Flags().
+	rootCmd.PersistentFlags().
 		StringSliceVar(&ignoreFiles, "ignore-file", nil, "Path to a custom ignore file. Can be specified multiple times.")
-	rootCmd.Flags().
+	rootCmd.PersistentFlags().
 		BoolVar(&noBinaryCheck, "no-binary-check", false, "Disable the binary file check.")
+	rootCmd.PersistentFlags().
+		StringVar(&maxFileSize, "max-file-size", "", "Skip files larger than this size, e.g. 100KB or 5MB.")
+	rootCmd.PersistentFlags().
+		StringVar(&minFileSize, "min-file-size", "", "Skip files smaller than this size.")
+	rootCmd.PersistentFlags().
+		IntVar(&maxLines, "max-lines", 0, "Skip files with more lines than this.")
+	rootCmd.PersistentFlags().
+		StringVar(&newerThan, "newer-than", "", "Only include files modified after a duration ago (36h, 7d, 2w), a date (2024-05-01) or a file's modification time.")
+	rootCmd.PersistentFlags().
+		StringVar(&olderThan, "older-than", "", "Only include files modified before a duration ago, a date or a file's modification time.")
+	rootCmd.PersistentFlags().
+		StringVar(&grep, "grep", "", "Only include files with a line matching this regular expression.")
+	rootCmd.PersistentFlags().
+		StringVar(&grepInvert, "grep-v", "", "Skip files with a line matching this regular expression.")
+	rootCmd.Flags().
+		IntVar(&grepContext, "grep-context", -1, "With --grep, only output the matching lines and this many lines around them.")
+	rootCmd.PersistentFlags().
+		BoolVar(&noDefaultIgnore, "no-default-ignores", false, "Include dependency directories, lockfiles and generated files that are skipped by default.")
+	rootCmd.PersistentFlags().
+		BoolVar(&allowSecrets, "allow-secret-files", false, "Include files that usually hold credentials, such as .env, id_rsa and *.pem, which are skipped by default.")
 	rootCmd.Flags().
 		StringVarP(&outputFile, "output", "o", "", "Write the output to a file instead of stdout.")
 	rootCmd.Flags().
 		StringVar(&template, "template", "", "A template string that defines the output format.")
+	rootCmd.Flags().
+		StringVar(&header, "header", "", "A template written once before all files.")
+	rootCmd.Flags().
+		StringVar(&footer, "footer", "", "A template written once after all files.")
+	rootCmd.Flags().
+		BoolVar(&showTree, "tree", false, "Write a directory tree of the selected files before their contents.")
+	rootCmd.Flags().
+		BoolVar(&treeIgnored, "tree-ignored", false, "Include excluded and ignored paths in the tree, marked with the rule that rejected them.")
+	rootCmd.Flags().
+		BoolVar(&goTemplate, "go-template", false, "Interpret the template as a Go text/template.")
+	rootCmd.Flags().
+		StringVar(&format, "format", "", "Structured output format instead of a template: "+strings.Join(processor.Formats, ", ")+".")
+	rootCmd.Flags().
+		IntVar(&maxTokens, "max-tokens", 0, "Maximum number of tokens in the output. Files that do not fit are omitted.")
+	rootCmd.PersistentFlags().
+		StringVar(&tokenizerName, "tokenizer", tokenizer.DefaultName, "Tokenizer used for counting: estimate, cl100k or o200k.")
+	rootCmd.Flags().
+		StringVar(&fitStrategy, "fit-strategy", budget.DefaultStrategy, "How to fit files into --max-tokens: "+strings.Join(budget.Names(), ", ")+".")
+	rootCmd.Flags().
+		IntVar(&maxLinesPerFile, "max-lines-per-file", 0, "Truncate files to this many lines.")
+	rootCmd.Flags().
+		StringVar(&maxBytesPerFile, "max-bytes-per-file", "", "Truncate files to this size, e.g. 8KB. Only whole lines are kept.")
+	rootCmd.Flags().
+		StringVar(&truncateMode, "truncate", "middle", "Which part of a truncated file to keep: "+strings.Join(processor.TruncateModes, ", ")+".")
+	rootCmd.Flags().
+		BoolVar(&outline, "outline", false, "Only output the declarations of source files, with function bodies elided. Supported for: "+strings.Join(processor.SummarizerExtensions(), ", ")+".")
+	rootCmd.Flags().
+		StringSliceVar(&outlineGlobs, "outline-glob", nil, "A glob pattern for files to outline, as with --outline. Can be specified multiple times.")
+	rootCmd.Flags().
+		StringSliceVar(&fullGlobs, "full-glob", nil, "A glob pattern for files output in full, as with --mode <glob>=full. Can be specified multiple times.")
+	rootCmd.Flags().
+		StringArrayVar(&modeFlags, "mode", nil, "A rule <glob>=<mode> that renders the matching files as "+strings.Join(processor.Modes, ", ")+". The first matching rule wins. Can be specified multiple times.")
+	rootCmd.Flags().
+		BoolVar(&stripComments, "strip-comments", false, "Remove comments from files in known languages, keeping string literals intact.")
+	rootCmd.Flags().
+		BoolVar(&collapseBlanks, "collapse-blank-lines", false, "Replace runs of blank lines with a single blank line.")
+	rootCmd.Flags().
+		BoolVar(&lineNumbers, "line-numbers", false, "Prefix each line of the content with its line number.")
+	rootCmd.Flags().
+		StringVar(&lineNumberFmt, "line-number-format", processor.DefaultLineNumberFormat, "Line number prefix, with {n} standing for the number.")
+	rootCmd.Flags().
+		IntVar(&lineNumberWidth, "line-number-width", 0, "Minimum width of line numbers. 0 fits the largest number in each file.")
+	rootCmd.Flags().
+		BoolVar(&noRedact, "no-redact", false, "Do not replace API keys, tokens, private keys and passwords with [REDACTED:type]. Patterns given with --redact still apply.")
+	rootCmd.Flags().
+		StringArrayVar(&redactPatterns, "redact", nil, "A regular expression whose matches are redacted as [REDACTED:custom]. If it has a group, only the group is redacted. Can be specified multiple times.")
+	rootCmd.Flags().
+		StringSliceVar(&priorityGlobs, "priority", nil, "A glob pattern for files kept first by the priority fit strategy. Can be specified multiple times.")
+	rootCmd.PersistentFlags().
+		IntVar(&followImports, "follow-imports", 0, "Add the files of the module-local packages imported by the selected Go files, following imports this many levels deep.")
+	rootCmd.PersistentFlags().Lookup("follow-imports").NoOptDefVal = "1"
+	rootCmd.PersistentFlags().
+		BoolVar(&followVendor, "follow-vendor", false, "With --follow-imports, also follow imports of other modules into the vendor directory.")
+	rootCmd.PersistentFlags().
+		BoolVar(&followModCache, "follow-mod-cache", false, "With --follow-imports, also follow imports of other modules into the module cache.")
+	rootCmd.Flags().
+		StringVar(&followMode, "follow-imports-mode", processor.ModeOutline, "Rendering mode of files added by --follow-imports that match no --mode rule: "+strings.Join(processor.Modes, ", ")+".")
+	rootCmd.PersistentFlags().
+		StringArrayVar(&callersOf, "callers-of", nil, "Add the Go files of the module that refer to a symbol, given as pkg.Name or pkg.Type.Method. Can be specified multiple times.")
+	rootCmd.PersistentFlags().
+		StringVar(&callersScope, "callers-scope", "file", "What --callers-of adds of each referring file: file, or function for only the declarations that refer to the symbol.")
+	rootCmd.PersistentFlags().
+		StringVar(&changedSince, "changed-since", "", "Only include files changed since HEAD forked from a git commit-ish, including uncommitted and untracked files.")
+	rootCmd.PersistentFlags().
+		BoolVar(&stagedOnly, "staged", false, "Only include files with staged changes.")
+	rootCmd.PersistentFlags().
+		BoolVar(&unstagedOnly, "unstaged", false, "Only include files with unstaged changes, and untracked files.")
+	rootCmd.PersistentFlags().
+		StringVar(&profileName, "profile", "", "Apply a named profile from the configuration files.")
+	rootCmd.PersistentFlags().
+		BoolVar(&noConfig, "no-config", false, "Ignore .ctxcat.yaml configuration files.")
+	rootCmd.PersistentFlags().
+		StringVar(&walkerName, "walker", "auto", "How directories are enumerated: auto (git ls-files inside repositories, otherwise the filesystem) or fs.")
+	rootCmd.PersistentFlags().
+		StringVar(&revision, "rev", "", "Read files from a git commit-ish instead of the working tree.")
+	rootCmd.Flags().
+		BoolVar(&withDiff, "with-diff", false, "Include each file's git diff alongside its content.")
+	rootCmd.Flags().
+		BoolVar(&diffOnly, "diff-only", false, "Include each file's git diff instead of its content.")
 	rootCmd.Flags().BoolVarP(&showVersion, "version", "v", false, "Show the version number.")
 }
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the repository and home directory configuration files.
+const FileName = ".ctxcat.yaml"
+
+// Settings maps command-line flag names to configured values. The special key
+// "paths" lists the input paths used when none are given.
+type Settings map[string]any
+
+// pathKeys are the settings whose values are file paths. Relative paths in a
+// configuration file are relative to the directory that holds it, and are
+// rewritten relative to the working directory when the file is read.
+var pathKeys = []string{"paths", "ignore-file", "output"}
+
+// File is the merged content of all configuration files.
+type File struct {
+	Settings Settings
+	Profiles map[string]Settings
+}
+
+// LoadFile reads and merges the configuration files that apply to dir.
+// Precedence: repository > home directory > XDG config directory.
+// The repository file is the first .ctxcat.yaml found walking up from dir,
+// stopping at the root of the git work tree.
+func LoadFile(dir string) (*File, error) {
+	merged := &File{Settings: Settings{}, Profiles: map[string]Settings{}}
+
+	var paths []string
+	if configDir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(configDir, "ctxcat", "config.yaml"))
+	}
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(homeDir, FileName))
+	}
+	if repoPath := findRepoFile(dir); repoPath != "" {
+		paths = append(paths, repoPath)
+	}
+
+	loaded := make(map[string]bool)
+	for _, path := range paths {
+		if loaded[path] {
+			continue
+		}
+		loaded[path] = true
+		layer, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if layer == nil {
+			continue
+		}
+		for key, value := range layer.Settings {
+			merged.Settings[key] = value
+		}
+		for name, profile := range layer.Profiles {
+			if merged.Profiles[name] == nil {
+				merged.Profiles[name] = Settings{}
+			}
+			for key, value := range profile {
+				merged.Profiles[name][key] = value
+			}
+		}
+	}
+	return merged, nil
+}
+
+// Resolve returns the settings with the named profile applied on top.
+// An empty name returns the settings without a profile.
+func (f *File) Resolve(profile string) (Settings, error) {
+	resolved := Settings{}
+	for key, value := range f.Settings {
+		resolved[key] = value
+	}
+	if profile == "" {
+		return resolved, nil
+	}
+
+	settings, ok := f.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(f.Profiles))
+		for name := range f.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("profile %q not found: no profiles are configured", profile)
+		}
+		return nil, fmt.Errorf("profile %q not found (available: %s)", profile, strings.Join(names, ", "))
+	}
+	for key, value := range settings {
+		resolved[key] = value
+	}
+	return resolved, nil
+}
+
+// findRepoFile looks for FileName in dir and its parents, up to the git work tree root.
+func findRepoFile(dir string) string {
+	current, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(current, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return ""
+		}
+		current = parent
+	}
+}
+
+// readFile parses a single configuration file. It returns nil if the file does not exist.
+func readFile(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	var raw map[string]any
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	file := &File{Settings: Settings{}, Profiles: map[string]Settings{}}
+	for key, value := range raw {
+		if key != "profiles" {
+			file.Settings[key] = value
+			continue
+		}
+		profiles, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("parsing config %s: profiles must be a mapping of names to settings", path)
+		}
+		for name, profile := range profiles {
+			settings, ok := profile.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("parsing config %s: profile %q must be a mapping of settings", path, name)
+			}
+			file.Profiles[name] = settings
+		}
+	}
+
+	resolvePaths(file.Settings, filepath.Dir(path))
+	for _, settings := range file.Profiles {
+		resolvePaths(settings, filepath.Dir(path))
+	}
+	return file, nil
+}
+
+// resolvePaths rewrites the relative values of pathKeys in settings, which are
+// relative to dir, to be relative to the working directory. Values that are not
+// strings are left for the flags to reject.
+func resolvePaths(settings Settings, dir string) {
+	resolve := func(value any) any {
+		p, ok := value.(string)
+		if !ok || p == "" || filepath.IsAbs(p) {
+			return value
+		}
+		return relativeToWd(filepath.Join(dir, p))
+	}
+	for _, key := range pathKeys {
+		switch value := settings[key].(type) {
+		case string:
+			settings[key] = resolve(value)
+		case []any:
+			resolved := make([]any, len(value))
+			for i, v := range value {
+				resolved[i] = resolve(v)
+			}
+			settings[key] = resolved
+		}
+	}
+}
+
+// relativeToWd returns path relative to the working directory, or absolute when
+// that is not possible.
+func relativeToWd(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return abs
+	}
+	if rel, err := filepath.Rel(wd, abs); err == nil {
+		return rel
+	}
+	return abs
+}
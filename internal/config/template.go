@@ -15,21 +15,58 @@ const (
 		"```\n" +
 		"=== File End: {path} ===\n\n"
 
+	// DiffTemplate replaces DefaultTemplate when diffs are included alongside the content.
+	DiffTemplate = "=== File Start: {path} ===\n" +
+		"```{extension}\n" +
+		"{content}\n" +
+		"```\n" +
+		"=== Diff: {path} ===\n" +
+		"```diff\n" +
+		"{diff}" +
+		"```\n" +
+		"=== File End: {path} ===\n\n"
+
+	// DiffOnlyTemplate replaces DefaultTemplate when diffs are included instead of the content.
+	DiffOnlyTemplate = "=== Diff: {path} ===\n" +
+		"```diff\n" +
+		"{diff}" +
+		"```\n\n"
+
 	templateFileName = ".contextgrep.template.txt"
+	headerFileName   = ".contextgrep.header.txt"
+	footerFileName   = ".contextgrep.footer.txt"
 )
 
 // LoadTemplate finds and returns the template string to use based on precedence.
 // Precedence: command-line flag > local file > home dir file > default.
 func LoadTemplate(cliTemplate string) (string, error) {
-	if cliTemplate != "" {
-		return cliTemplate, nil
+	return loadText(cliTemplate, templateFileName, "template.txt", DefaultTemplate)
+}
+
+// LoadHeader returns the text written before all files, using the same precedence
+// as LoadTemplate. The default is an empty header.
+func LoadHeader(cliHeader string) (string, error) {
+	return loadText(cliHeader, headerFileName, "header.txt", "")
+}
+
+// LoadFooter returns the text written after all files, using the same precedence
+// as LoadTemplate. The default is an empty footer.
+func LoadFooter(cliFooter string) (string, error) {
+	return loadText(cliFooter, footerFileName, "footer.txt", "")
+}
+
+// loadText looks for fileName in the working and home directories, and for
+// configName in ~/.config/contextgrep, returning the first one found.
+func loadText(cliValue, fileName, configName, defaultValue string) (string, error) {
+	if cliValue != "" {
+		return cliValue, nil
 	}
 
 	// Check current working directory
 	cwd, err := os.Getwd()
 	if err == nil {
-		localTemplatePath := filepath.Join(cwd, templateFileName)
-		if content, err := os.ReadFile(localTemplatePath); err == nil {
+		localPath := filepath.Join(cwd, fileName)
+		if content, err := os.ReadFile(localPath); err == nil {
 			return string(content), nil
 		}
 	}
@@ -37,18 +74,18 @@ func LoadTemplate(cliTemplate string) (string, error) {
 	// Check home directory
 	homeDir, err := os.UserHomeDir()
 	if err == nil {
-		homeTemplatePath := filepath.Join(homeDir, templateFileName)
-		if content, err := os.ReadFile(homeTemplatePath); err == nil {
+		homePath := filepath.Join(homeDir, fileName)
+		if content, err := os.ReadFile(homePath); err == nil {
 			return string(content), nil
 		}
 
 		// Check .config directory in home
-		configTemplatePath := filepath.Join(homeDir, ".config", "contextgrep", "template.txt")
-		if content, err := os.ReadFile(configTemplatePath); err == nil {
+		configPath := filepath.Join(homeDir, ".config", "contextgrep", configName)
+		if content, err := os.ReadFile(configPath); err == nil {
 			return string(content), nil
 		}
 	}
 
-	// Return the default if no custom template is found
-	return DefaultTemplate, nil
+	// Return the default if no custom file is found
+	return defaultValue, nil
 }
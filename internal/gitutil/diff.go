@@ -0,0 +1,190 @@
+package gitutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DiffSelector chooses which changes a file must have to be selected.
+// When several fields are set, a file matching any of them is selected.
+type DiffSelector struct {
+	Since    string // Changes from the merge base of this commit-ish and HEAD to the working tree
+	Staged   bool   // Changes in the index that are not committed
+	Unstaged bool   // Changes in the working tree that are not staged, and untracked files
+}
+
+// IsZero reports whether no selector is set.
+func (s DiffSelector) IsZero() bool {
+	return s.Since == "" && !s.Staged && !s.Unstaged
+}
+
+// diffArgs returns the "git diff" arguments that compare the selected states in
+// the repository containing dir.
+func (s DiffSelector) diffArgs(dir string) ([]string, error) {
+	switch {
+	case s.Since != "":
+		base, err := mergeBase(dir, s.Since)
+		if err != nil {
+			return nil, err
+		}
+		return []string{base}, nil
+	case s.Staged && s.Unstaged:
+		return []string{"HEAD"}, nil
+	case s.Staged:
+		return []string{"--cached"}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// mergeBase returns the commit where HEAD forked from rev, so that changes made on
+// rev after that are not taken for changes on the current branch.
+func mergeBase(dir, rev string) (string, error) {
+	out, err := run(dir, "merge-base", rev, "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// TopLevel returns the root directory of the work tree containing dir.
+func TopLevel(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Prefix returns the path of dir relative to the root of its work tree, with forward
+// slashes, or "." at the root. Unlike a path computed from dir and TopLevel, it is
+// correct when dir is reached through a symbolic link, which git resolves in TopLevel.
+func Prefix(dir string) (string, error) {
+	out, err := run(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", err
+	}
+	return path.Clean("./" + strings.TrimSpace(string(out))), nil
+}
+
+// ChangedFiles returns the paths, relative to dir, of files with changes matching the selector.
+func ChangedFiles(dir string, s DiffSelector) ([]string, error) {
+	root, err := TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := Prefix(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var lists [][]string
+	if s.Since != "" {
+		base, err := mergeBase(root, s.Since)
+		if err != nil {
+			return nil, err
+		}
+		names, err := nulList(root, "diff", "--name-only", "-z", base, "--")
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, names)
+	}
+	if s.Staged {
+		names, err := nulList(root, "diff", "--name-only", "-z", "--cached", "--")
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, names)
+	}
+	if s.Unstaged {
+		names, err := nulList(root, "diff", "--name-only", "-z", "--")
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, names)
+	}
+	if s.Since != "" || s.Unstaged {
+		// New files are not part of "git diff" until they are added.
+		names, err := nulList(root, "ls-files", "--others", "--exclude-standard", "-z")
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, names)
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, names := range lists {
+		for _, name := range names {
+			rel, err := filepath.Rel(filepath.FromSlash(prefix), filepath.FromSlash(name))
+			if err != nil || seen[rel] {
+				continue
+			}
+			seen[rel] = true
+			result = append(result, rel)
+		}
+	}
+	return result, nil
+}
+
+// FileDiff returns the unified diff of path for the selected changes.
+// Untracked files are diffed against an empty file.
+func FileDiff(path string, s DiffSelector) (string, error) {
+	dir := filepath.Dir(path)
+	base := filepath.Base(path)
+
+	selected, err := s.diffArgs(dir)
+	if err != nil {
+		return "", err
+	}
+	args := append([]string{"diff", "--no-color"}, selected...)
+	out, err := run(dir, append(args, "--", base)...)
+	if err != nil {
+		return "", err
+	}
+	if len(out) > 0 || (s.Since == "" && !s.Unstaged) {
+		return string(out), nil
+	}
+
+	// Untracked files have no diff against the index, so compare with nothing.
+	tracked, err := run(dir, "ls-files", "--", base)
+	if err != nil || len(bytes.TrimSpace(tracked)) > 0 {
+		return string(out), err
+	}
+	return noIndexDiff(dir, base)
+}
+
+// noIndexDiff diffs a file against the null device. "git diff --no-index" exits
+// with status 1 when there are differences, which is not an error here.
+func noIndexDiff(dir, base string) (string, error) {
+	cmd := newCommand(dir, "diff", "--no-color", "--no-index", "--", os.DevNull, base)
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		err = nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("git diff --no-index %s: %w", base, err)
+	}
+	return string(out), nil
+}
+
+// nulList runs git and splits its NUL-separated output.
+func nulList(dir string, args ...string) ([]string, error) {
+	out, err := run(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	var items []string
+	for _, item := range strings.Split(string(out), "\x00") {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
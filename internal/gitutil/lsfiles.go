@@ -0,0 +1,231 @@
+package gitutil
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LsFiles lists files exactly as git sees them: tracked files plus untracked files
+// that are not ignored by .gitignore, .git/info/exclude or core.excludesFile.
+// Each repository is listed once and cached.
+type LsFiles struct {
+	mu      sync.Mutex
+	roots   map[string]repoDir // By directory
+	listing map[string]*repoListing
+}
+
+// repoDir locates a directory in its work tree.
+type repoDir struct {
+	root string // Work tree root, "" outside a repository
+	dir  string // The directory under root, with symbolic links resolved as git does
+}
+
+// gitlinkMode is the mode of a submodule in the index.
+const gitlinkMode = "160000"
+
+type repoListing struct {
+	files   []string          // Absolute paths, sorted
+	ignored map[string]string // Absolute path of an ignored file or directory -> rule
+}
+
+// NewLsFiles creates an empty git file lister.
+func NewLsFiles() *LsFiles {
+	return &LsFiles{
+		roots:   make(map[string]repoDir),
+		listing: make(map[string]*repoListing),
+	}
+}
+
+// ListFiles returns the files under path (a directory or a single file) that git
+// considers part of the work tree, and the ignored paths under it mapped to the
+// rule that ignores them. Returned paths are built on path, like filepath.WalkDir.
+// ok is false when path is not inside a git work tree, or when git lists nothing
+// under it, so that the caller can walk the filesystem instead.
+func (l *LsFiles) ListFiles(path string) (files []string, ignored map[string]string, ok bool, err error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	// Git reports paths with symbolic links resolved, so abs is compared with the
+	// listing as git sees it.
+	root, abs := l.rootOf(abs)
+	if root == "" {
+		return nil, nil, false, nil
+	}
+	listing, err := l.list(root)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	toPath := func(f string) string {
+		if f == abs {
+			return path
+		}
+		rel, _ := filepath.Rel(abs, f)
+		return filepath.Join(path, rel)
+	}
+
+	// Files are sorted, so everything under abs is one contiguous run.
+	dirPrefix := abs + string(filepath.Separator)
+	start := sort.SearchStrings(listing.files, abs)
+	for _, f := range listing.files[start:] {
+		if f != abs && !strings.HasPrefix(f, dirPrefix) {
+			break
+		}
+		files = append(files, toPath(f))
+	}
+
+	ignored = make(map[string]string)
+	for f, rule := range listing.ignored {
+		if f == abs || strings.HasPrefix(f, dirPrefix) {
+			ignored[toPath(f)] = rule
+		}
+	}
+	if len(files) == 0 && len(ignored) == 0 {
+		return nil, nil, false, nil
+	}
+	return files, ignored, true, nil
+}
+
+// rootOf returns the work tree root containing abs, or "" if there is none, and abs
+// as git sees it under that root.
+func (l *LsFiles) rootOf(abs string) (string, string) {
+	dir, name := abs, ""
+	if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+		dir, name = filepath.Split(abs)
+		dir = filepath.Clean(dir)
+	}
+
+	l.mu.Lock()
+	repo, cached := l.roots[dir]
+	l.mu.Unlock()
+	if !cached {
+		if out, err := run(dir, "rev-parse", "--show-toplevel", "--show-prefix"); err == nil {
+			lines := strings.Split(string(out), "\n")
+			repo.root = filepath.Clean(lines[0])
+			repo.dir = filepath.Join(repo.root, filepath.FromSlash(lines[1]))
+		}
+		l.mu.Lock()
+		l.roots[dir] = repo
+		l.mu.Unlock()
+	}
+	if repo.root == "" {
+		return "", abs
+	}
+	return repo.root, filepath.Join(repo.dir, name)
+}
+
+// list runs "git ls-files" for the repository at root, once.
+func (l *LsFiles) list(root string) (*repoListing, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.load(root)
+}
+
+// load lists the repository at root and the submodules checked out in it. It is
+// called with l.mu held.
+func (l *LsFiles) load(root string) (*repoListing, error) {
+	if listing, ok := l.listing[root]; ok {
+		return listing, nil
+	}
+
+	// Entries are "<mode> <object> <stage>\t<name>".
+	entries, err := nulList(root, "ls-files", "--cached", "--stage", "-z")
+	if err != nil {
+		return nil, err
+	}
+	others, err := nulList(root, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	listing := &repoListing{ignored: make(map[string]string)}
+	seen := make(map[string]bool, len(entries)+len(others))
+	add := func(name string) {
+		// Unmerged files are listed once per stage.
+		if !seen[name] {
+			seen[name] = true
+			listing.files = append(listing.files, filepath.Join(root, filepath.FromSlash(name)))
+		}
+	}
+	for _, entry := range entries {
+		info, name, ok := strings.Cut(entry, "\t")
+		if !ok {
+			continue
+		}
+		if !strings.HasPrefix(info, gitlinkMode+" ") {
+			add(name)
+			continue
+		}
+		// A submodule is listed as a single entry. Its files are listed from its own
+		// repository, if it is checked out.
+		dir := filepath.Join(root, filepath.FromSlash(name))
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+			continue
+		}
+		sub, err := l.load(dir)
+		if err != nil {
+			return nil, err
+		}
+		listing.files = append(listing.files, sub.files...)
+		for f, rule := range sub.ignored {
+			listing.ignored[f] = rule
+		}
+	}
+	for _, name := range others {
+		add(name)
+	}
+	sort.Strings(listing.files)
+
+	ignored, err := nulList(root, "ls-files", "--others", "--ignored", "--exclude-standard", "--directory", "-z")
+	if err != nil {
+		return nil, err
+	}
+	if len(ignored) > 0 {
+		rules, err := explainIgnored(root, ignored)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range ignored {
+			abs := filepath.Join(root, filepath.FromSlash(strings.TrimSuffix(name, "/")))
+			listing.ignored[abs] = rules[name]
+		}
+	}
+
+	l.listing[root] = listing
+	return listing, nil
+}
+
+// explainIgnored maps each ignored path to "<source>:<line>: <pattern>" using "git check-ignore".
+func explainIgnored(root string, paths []string) (map[string]string, error) {
+	cmd := newCommand(root, "check-ignore", "--verbose", "--no-index", "-z", "--stdin")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\x00") + "\x00")
+	out, err := cmd.Output()
+	if err != nil && len(out) == 0 {
+		// check-ignore exits with 1 when nothing matched; no detail is available.
+		return map[string]string{}, nil
+	}
+
+	cwd, _ := os.Getwd()
+	if real, err := filepath.EvalSymlinks(cwd); err == nil {
+		// Sources are under root, in which git has resolved symbolic links.
+		cwd = real
+	}
+	fields := strings.Split(string(out), "\x00")
+	rules := make(map[string]string)
+	// Records are <source> NUL <linenum> NUL <pattern> NUL <pathname> NUL.
+	for i := 0; i+3 < len(fields); i += 4 {
+		source := fields[i]
+		if !filepath.IsAbs(source) {
+			source = filepath.Join(root, filepath.FromSlash(source))
+		}
+		if rel, err := filepath.Rel(cwd, source); err == nil && cwd != "" {
+			source = rel
+		}
+		rules[fields[i+3]] = filepath.ToSlash(source) + ":" + fields[i+1] + ": " + fields[i+2]
+	}
+	return rules, nil
+}
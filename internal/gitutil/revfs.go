@@ -0,0 +1,394 @@
+package gitutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// RevFS reads files from a commit in the git object database instead of the working
+// tree. Paths follow operating system conventions and are resolved against the
+// working directory, so it can stand in for the disk when selecting and formatting files.
+type RevFS struct {
+	root   string // Absolute path of the work tree root, with symbolic links resolved
+	cwd    string // Absolute path of the working directory, as given
+	prefix string // Working directory relative to root, with forward slashes
+	tree   *revTree
+}
+
+// NewRevFS lists the tree of rev for the repository containing dir.
+func NewRevFS(dir, rev string) (*RevFS, error) {
+	root, err := TopLevel(dir)
+	if err != nil {
+		return nil, err
+	}
+	cwd, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := Prefix(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := loadTree(root, rev)
+	if err != nil {
+		return nil, err
+	}
+	return &RevFS{root: root, cwd: cwd, prefix: prefix, tree: tree}, nil
+}
+
+// toTree converts a working directory path or pattern to a path in the tree. It
+// returns false if the path is outside the work tree.
+func (r *RevFS) toTree(name string) (string, bool) {
+	if !filepath.IsAbs(name) {
+		rel := path.Join(r.prefix, filepath.ToSlash(name))
+		return rel, !escapes(rel)
+	}
+	// Absolute paths can go through the working directory as given, which may be
+	// reached through a symbolic link, or through the resolved work tree root.
+	for _, base := range []struct{ dir, prefix string }{{r.cwd, r.prefix}, {r.root, "."}} {
+		if rel, err := filepath.Rel(base.dir, name); err == nil {
+			if rel := path.Join(base.prefix, filepath.ToSlash(rel)); !escapes(rel) {
+				return rel, true
+			}
+		}
+	}
+	return "", false
+}
+
+// escapes reports whether a clean slash-separated relative path leaves its base.
+func escapes(rel string) bool {
+	return rel == ".." || strings.HasPrefix(rel, "../")
+}
+
+// treePath converts a working directory path to a path in the tree.
+func (r *RevFS) treePath(name string) (string, error) {
+	rel, ok := r.toTree(name)
+	if !ok {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return rel, nil
+}
+
+// Open opens a file or directory at the revision.
+func (r *RevFS) Open(name string) (fs.File, error) {
+	tp, err := r.treePath(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.tree.Open(tp)
+}
+
+// Stat returns the file info for a path at the revision.
+func (r *RevFS) Stat(name string) (fs.FileInfo, error) {
+	tp, err := r.treePath(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.tree.Stat(tp)
+}
+
+// ReadFile reads a file's content at the revision.
+func (r *RevFS) ReadFile(name string) ([]byte, error) {
+	tp, err := r.treePath(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.tree.ReadFile(tp)
+}
+
+// WalkDir walks the tree rooted at root, calling fn with working directory paths.
+func (r *RevFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	tp, err := r.treePath(root)
+	if err != nil {
+		return fn(root, nil, err)
+	}
+	return fs.WalkDir(r.tree, tp, func(p string, d fs.DirEntry, err error) error {
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, tp), "/")
+		if tp == "." {
+			rel = p
+		}
+		return fn(filepath.Join(root, filepath.FromSlash(rel)), d, err)
+	})
+}
+
+// Glob returns the working directory paths at the revision that match pattern.
+func (r *RevFS) Glob(pattern string) ([]string, error) {
+	pattern, ok := r.toTree(pattern)
+	if !ok {
+		return nil, nil
+	}
+
+	matches, err := doublestar.Glob(r.tree, pattern)
+	if err != nil {
+		return nil, err
+	}
+	for i, match := range matches {
+		rel, err := filepath.Rel(filepath.FromSlash(r.prefix), filepath.FromSlash(match))
+		if err != nil {
+			return nil, err
+		}
+		matches[i] = rel
+	}
+	return matches, nil
+}
+
+// revTree is an fs.FS over the entries of a git tree, keyed by slash-separated paths.
+type revTree struct {
+	modTime  time.Time
+	blobs    *catFile
+	entries  map[string]*revEntry
+	children map[string][]*revEntry
+}
+
+type revEntry struct {
+	path   string
+	mode   fs.FileMode
+	object string
+	size   int64
+	tree   *revTree
+}
+
+// loadTree lists every entry of rev with "git ls-tree".
+func loadTree(root, rev string) (*revTree, error) {
+	commitTime, err := run(root, "show", "-s", "--format=%ct", rev+"^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	seconds, err := strconv.ParseInt(strings.TrimSpace(string(commitTime)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("reading commit time of %s: %w", rev, err)
+	}
+
+	items, err := nulList(root, "ls-tree", "-r", "-t", "-l", "-z", "--full-tree", rev)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &revTree{
+		modTime:  time.Unix(seconds, 0),
+		blobs:    &catFile{root: root, blobs: make(map[string][]byte)},
+		entries:  map[string]*revEntry{".": {path: ".", mode: fs.ModeDir | 0o755}},
+		children: make(map[string][]*revEntry),
+	}
+	t.entries["."].tree = t
+
+	for _, item := range items {
+		// <mode> SP <type> SP <object> SP+ <size> TAB <path>
+		meta, name, ok := strings.Cut(item, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 4 {
+			continue
+		}
+		entry := &revEntry{path: name, object: fields[2], tree: t}
+		switch fields[1] {
+		case "tree":
+			entry.mode = fs.ModeDir | 0o755
+		case "blob":
+			entry.mode = 0o644
+			if fields[0] == "100755" {
+				entry.mode = 0o755
+			}
+			entry.size, _ = strconv.ParseInt(fields[3], 10, 64)
+		default:
+			// Submodules have no content in this repository.
+			continue
+		}
+		t.entries[name] = entry
+		parent := path.Dir(name)
+		t.children[parent] = append(t.children[parent], entry)
+	}
+
+	for _, children := range t.children {
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].path < children[j].path
+		})
+	}
+	return t, nil
+}
+
+func (t *revTree) lookup(op, name string) (*revEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := t.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return entry, nil
+}
+
+// Open implements fs.FS.
+func (t *revTree) Open(name string) (fs.File, error) {
+	entry, err := t.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir() {
+		return &revDir{entry: entry, children: t.children[name]}, nil
+	}
+	content, err := t.readBlob(entry)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &revFile{entry: entry, Reader: bytes.NewReader(content)}, nil
+}
+
+// Stat implements fs.StatFS.
+func (t *revTree) Stat(name string) (fs.FileInfo, error) {
+	return t.lookup("stat", name)
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (t *revTree) ReadDir(name string) ([]fs.DirEntry, error) {
+	entry, err := t.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+	children := t.children[name]
+	result := make([]fs.DirEntry, len(children))
+	for i, child := range children {
+		result[i] = fs.FileInfoToDirEntry(child)
+	}
+	return result, nil
+}
+
+// ReadFile implements fs.ReadFileFS.
+func (t *revTree) ReadFile(name string) ([]byte, error) {
+	entry, err := t.lookup("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fmt.Errorf("is a directory")}
+	}
+	return t.readBlob(entry)
+}
+
+func (t *revTree) readBlob(entry *revEntry) ([]byte, error) {
+	return t.blobs.read(entry.object)
+}
+
+// catFile reads blobs through a single "git cat-file --batch" process, started on
+// first use, and keeps them by object ID, since a file is often read more than once.
+// The process exits with ctxcat, when its standard input is closed.
+type catFile struct {
+	root   string
+	mu     sync.Mutex
+	stdin  io.Writer
+	stdout *bufio.Reader
+	blobs  map[string][]byte
+}
+
+func (c *catFile) read(object string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if content, ok := c.blobs[object]; ok {
+		return bytes.Clone(content), nil
+	}
+	if c.stdin == nil {
+		cmd := newCommand(c.root, "cat-file", "--batch")
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("git cat-file --batch: %w", err)
+		}
+		c.stdin, c.stdout = stdin, bufio.NewReader(stdout)
+	}
+
+	if _, err := fmt.Fprintln(c.stdin, object); err != nil {
+		return nil, fmt.Errorf("git cat-file --batch: %w", err)
+	}
+	// The content follows a "<object> <type> <size>" line and is ended by a newline.
+	header, err := c.stdout.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file --batch: %w", err)
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("git cat-file --batch: %s", strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file --batch: %s", strings.TrimSpace(header))
+	}
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(c.stdout, content); err != nil {
+		return nil, fmt.Errorf("git cat-file --batch: %w", err)
+	}
+	content = content[:size]
+	c.blobs[object] = content
+	return bytes.Clone(content), nil
+}
+
+// revEntry implements fs.FileInfo.
+func (e *revEntry) Name() string       { return path.Base(e.path) }
+func (e *revEntry) Size() int64        { return e.size }
+func (e *revEntry) Mode() fs.FileMode  { return e.mode }
+func (e *revEntry) ModTime() time.Time { return e.tree.modTime }
+func (e *revEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *revEntry) Sys() any           { return nil }
+
+// revFile is an open blob.
+type revFile struct {
+	entry *revEntry
+	*bytes.Reader
+}
+
+func (f *revFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *revFile) Close() error               { return nil }
+
+// revDir is an open tree.
+type revDir struct {
+	entry    *revEntry
+	children []*revEntry
+	offset   int
+}
+
+func (d *revDir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+func (d *revDir) Close() error               { return nil }
+
+func (d *revDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.entry.path, Err: fmt.Errorf("is a directory")}
+}
+
+func (d *revDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.children[d.offset:]
+	if n > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(remaining) {
+		remaining = remaining[:n]
+	}
+	d.offset += len(remaining)
+	result := make([]fs.DirEntry, len(remaining))
+	for i, child := range remaining {
+		result[i] = fs.FileInfoToDirEntry(child)
+	}
+	return result, nil
+}
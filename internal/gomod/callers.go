@@ -0,0 +1,368 @@
+package gomod
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Symbol is a package-level function, type, variable or constant, or a method,
+// e.g. "store.Get" or "store.DB.Query".
+type Symbol struct {
+	Package string // Import path, or its last elements, e.g. "store" or "example.com/app/internal/store"
+	Type    string // Receiver type of a method, empty otherwise
+	Name    string
+}
+
+var symbolName = regexp.MustCompile(`^([\pL_][\pL\pN_]*)(?:\.([\pL_][\pL\pN_]*))?$`)
+
+// ParseSymbol parses a symbol of the form "pkg.Name" or "pkg.Type.Method", where pkg
+// is an import path or its last elements.
+func ParseSymbol(s string) (Symbol, error) {
+	slash := strings.LastIndex(s, "/")
+	dot := strings.Index(s[slash+1:], ".")
+	if dot <= 0 {
+		return Symbol{}, fmt.Errorf("invalid symbol %q (expected pkg.Name or pkg.Type.Method)", s)
+	}
+	dot += slash + 1
+	m := symbolName.FindStringSubmatch(s[dot+1:])
+	if m == nil {
+		return Symbol{}, fmt.Errorf("invalid symbol %q (expected pkg.Name or pkg.Type.Method)", s)
+	}
+	sym := Symbol{Package: s[:dot], Name: m[1]}
+	if m[2] != "" {
+		sym.Type, sym.Name = m[1], m[2]
+	}
+	return sym, nil
+}
+
+func (s Symbol) String() string {
+	if s.Type != "" {
+		return s.Package + "." + s.Type + "." + s.Name
+	}
+	return s.Package + "." + s.Name
+}
+
+// inPackage reports whether importPath is the package of the symbol.
+func (s Symbol) inPackage(importPath string) bool {
+	return importPath == s.Package || strings.HasSuffix(importPath, "/"+s.Package)
+}
+
+// is reports whether obj, an object found by the type checker, is the symbol.
+func (s Symbol) is(obj types.Object) bool {
+	if obj.Pkg() == nil || obj.Name() != s.Name || !s.inPackage(obj.Pkg().Path()) {
+		return false
+	}
+	if s.Type == "" {
+		return obj.Parent() == obj.Pkg().Scope()
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+	t := recv.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	return ok && named.Obj().Name() == s.Type
+}
+
+// Reference is a top-level declaration that refers to a symbol.
+type Reference struct {
+	File       string // Path of the Go file, relative to the working directory when inside it
+	Start, End int    // Lines of the declaration, including its doc comment
+}
+
+// sourcePackage is a package of a module, parsed from source.
+type sourcePackage struct {
+	path   string
+	name   string
+	files  []*ast.File // Files of the package
+	tests  []*ast.File // Test files of the package itself
+	xtests []*ast.File // Test files of the external test package, "<name>_test"
+}
+
+// FindReferences returns the declarations in the Go files of module m that refer to
+// sym, test files included. Files are those built for the current platform.
+//
+// When the symbol's package is in the module, the module is type-checked from
+// source with go/types, which finds every use, including method calls and uses
+// within the package itself. Packages outside the module are not loaded, so uses of
+// their symbols are found syntactically, as qualified identifiers such as
+// "http.Get", and for methods, method expressions such as "http.Client.Do".
+func FindReferences(m *Module, sym Symbol) ([]Reference, error) {
+	fset := token.NewFileSet()
+	packages, err := loadPackages(fset, m)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*ast.File)
+	local := false
+	for _, p := range packages {
+		for _, group := range [][]*ast.File{p.files, p.tests, p.xtests} {
+			for _, f := range group {
+				files[fset.Position(f.Package).Filename] = f
+			}
+		}
+		local = local || sym.inPackage(p.path)
+	}
+
+	found := make(map[Reference]bool)
+	add := func(pos token.Pos) {
+		position := fset.Position(pos)
+		if f := files[position.Filename]; f != nil {
+			if start, end, ok := declaration(fset, f, pos); ok {
+				found[Reference{File: position.Filename, Start: start, End: end}] = true
+			}
+		}
+	}
+
+	if local {
+		uses := typeCheck(fset, packages)
+		for ident, obj := range uses {
+			if sym.is(obj) {
+				add(ident.Pos())
+			}
+		}
+	}
+	for _, f := range files {
+		for _, pos := range qualifiedUses(f, sym, packages) {
+			add(pos)
+		}
+	}
+
+	references := make([]Reference, 0, len(found))
+	paths := make([]string, 0, len(found))
+	for ref := range found {
+		references = append(references, ref)
+		paths = append(paths, ref.File)
+	}
+	if err := relativePaths(paths); err != nil {
+		return nil, err
+	}
+	for i := range references {
+		references[i].File = paths[i]
+	}
+	sort.Slice(references, func(i, j int) bool {
+		a, b := references[i], references[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Start < b.Start
+	})
+	return references, nil
+}
+
+// loadPackages parses the packages of module m, by import path. The vendor and
+// testdata directories, hidden directories and nested modules are skipped.
+func loadPackages(fset *token.FileSet, m *Module) (map[string]*sourcePackage, error) {
+	packages := make(map[string]*sourcePackage)
+	err := filepath.WalkDir(m.Dir, func(dir string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if dir != m.Dir {
+			name := d.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		pkg, err := build.Default.ImportDir(dir, 0)
+		if pkg == nil || (err != nil && len(pkg.GoFiles)+len(pkg.TestGoFiles)+len(pkg.XTestGoFiles) == 0) {
+			return nil
+		}
+		rel, err := filepath.Rel(m.Dir, dir)
+		if err != nil {
+			return err
+		}
+		p := &sourcePackage{path: m.Path, name: pkg.Name}
+		if rel != "." {
+			p.path = path.Join(m.Path, filepath.ToSlash(rel))
+		}
+		parse := func(names []string) []*ast.File {
+			var files []*ast.File
+			for _, name := range names {
+				// A file with syntax errors is still searched as far as it parses.
+				if f, _ := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments); f != nil {
+					files = append(files, f)
+				}
+			}
+			return files
+		}
+		p.files = parse(append(pkg.GoFiles, pkg.CgoFiles...))
+		p.tests = parse(pkg.TestGoFiles)
+		p.xtests = parse(pkg.XTestGoFiles)
+		packages[p.path] = p
+		return nil
+	})
+	return packages, err
+}
+
+// sourceImporter type-checks the packages of a module from source as they are
+// imported. Packages outside the module cannot be imported.
+type sourceImporter struct {
+	fset     *token.FileSet
+	packages map[string]*sourcePackage
+	checked  map[string]*types.Package // nil while a package is being checked
+	uses     map[*ast.Ident]types.Object
+}
+
+func (imp *sourceImporter) Import(importPath string) (*types.Package, error) {
+	if pkg, ok := imp.checked[importPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", importPath)
+		}
+		return pkg, nil
+	}
+	p, ok := imp.packages[importPath]
+	if !ok {
+		return nil, fmt.Errorf("package %s is not in the module", importPath)
+	}
+	imp.checked[importPath] = nil
+	pkg := imp.check(importPath, p.files)
+	imp.checked[importPath] = pkg
+	return pkg, nil
+}
+
+// check type-checks files as the package importPath. Type errors, such as those
+// caused by imports from outside the module, are ignored: the check goes on and
+// records what it can.
+func (imp *sourceImporter) check(importPath string, files []*ast.File) *types.Package {
+	config := types.Config{
+		Importer:    imp,
+		FakeImportC: true,
+		Error:       func(error) {},
+	}
+	pkg, _ := config.Check(importPath, imp.fset, files, &types.Info{Uses: imp.uses})
+	return pkg
+}
+
+// typeCheck type-checks all packages, test packages included, and returns the
+// objects their identifiers refer to.
+func typeCheck(fset *token.FileSet, packages map[string]*sourcePackage) map[*ast.Ident]types.Object {
+	imp := &sourceImporter{
+		fset:     fset,
+		packages: packages,
+		checked:  make(map[string]*types.Package),
+		uses:     make(map[*ast.Ident]types.Object),
+	}
+	for importPath, p := range packages {
+		if _, err := imp.Import(importPath); err != nil {
+			continue
+		}
+		// The test files are checked with a copy of the package. Its objects are
+		// distinct from those of the imported package, which Symbol.is allows for.
+		if len(p.tests) > 0 {
+			imp.check(importPath, append(append([]*ast.File(nil), p.files...), p.tests...))
+		}
+		if len(p.xtests) > 0 {
+			imp.check(importPath+"_test", p.xtests)
+		}
+	}
+	return imp.uses
+}
+
+// qualifiedUses returns the positions in f of identifiers qualified with the name
+// of an import of sym's package that refer to sym.
+func qualifiedUses(f *ast.File, sym Symbol, packages map[string]*sourcePackage) []token.Pos {
+	names := make(map[string]bool)
+	for _, spec := range f.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || !sym.inPackage(importPath) {
+			continue
+		}
+		name := packageName(importPath, packages)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		names[name] = true
+	}
+	delete(names, "_")
+	delete(names, ".")
+	if len(names) == 0 {
+		return nil
+	}
+
+	// qualifier reports whether expr is an import name, rather than a local variable.
+	qualifier := func(expr ast.Expr) bool {
+		ident, ok := expr.(*ast.Ident)
+		return ok && ident.Obj == nil && names[ident.Name]
+	}
+	var uses []token.Pos
+	ast.Inspect(f, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != sym.Name {
+			return true
+		}
+		if sym.Type == "" && qualifier(sel.X) {
+			uses = append(uses, sel.Pos())
+		} else if typ, ok := sel.X.(*ast.SelectorExpr); ok && sym.Type != "" && typ.Sel.Name == sym.Type && qualifier(typ.X) {
+			uses = append(uses, sel.Pos())
+		}
+		return true
+	})
+	return uses
+}
+
+// packageName returns the name of the package importPath, which is parsed for the
+// packages of the module and guessed from the import path for others.
+func packageName(importPath string, packages map[string]*sourcePackage) string {
+	if p, ok := packages[importPath]; ok {
+		return p.name
+	}
+	elements := strings.Split(importPath, "/")
+	name := elements[len(elements)-1]
+	// Major version suffixes are not part of the name: "example.org/lib/v2", "gopkg.in/yaml.v3".
+	if len(elements) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elements[len(elements)-2]
+	}
+	name, _, _ = strings.Cut(name, ".")
+	return name
+}
+
+// declaration returns the lines of the top-level declaration of f that contains pos,
+// including its doc comment.
+func declaration(fset *token.FileSet, f *ast.File, pos token.Pos) (int, int, bool) {
+	for _, decl := range f.Decls {
+		if pos < decl.Pos() || pos >= decl.End() {
+			continue
+		}
+		start := decl.Pos()
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Doc != nil {
+				start = decl.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if decl.Doc != nil {
+				start = decl.Doc.Pos()
+			}
+		}
+		return fset.Position(start).Line, fset.Position(decl.End()).Line, true
+	}
+	return 0, 0, false
+}
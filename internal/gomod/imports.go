@@ -0,0 +1,195 @@
+package gomod
+
+import (
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Resolver follows the imports of Go files to the files of the imported packages.
+// Only packages of the module containing the importing file, and of modules it
+// replaces with a directory, are resolved by default; the standard library is
+// never followed.
+type Resolver struct {
+	// Vendor resolves packages from outside the module in its vendor directory.
+	Vendor bool
+	// ModCache resolves packages from outside the module in the module cache, at
+	// the version required by go.mod or the module version that replaces it.
+	ModCache bool
+
+	// Modules found so far, by directory.
+	modules map[string]*Module
+}
+
+// Follow returns the files of the packages imported by the Go files among files,
+// and of the packages those import, up to depth levels of imports. Test files and
+// files excluded by build constraints are left out, as are files already in files.
+// Returned paths are relative to the working directory when they are inside it.
+func (r *Resolver) Follow(files []string, depth int) ([]string, error) {
+	seen := make(map[string]bool)
+	var frontier []string
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		seen[abs] = true
+		if filepath.Ext(file) == ".go" {
+			frontier = append(frontier, abs)
+		}
+	}
+
+	var added []string
+	followed := make(map[string]bool)
+	for level := 0; level < depth && len(frontier) > 0; level++ {
+		var next []string
+		for _, file := range frontier {
+			m, err := r.module(filepath.Dir(file))
+			if err != nil {
+				return nil, err
+			}
+			if m == nil {
+				continue
+			}
+			for _, importPath := range parseImports(file) {
+				dir, ok := r.packageDir(m, importPath)
+				if !ok || followed[dir] {
+					continue
+				}
+				followed[dir] = true
+				for _, pkgFile := range packageFiles(dir) {
+					if !seen[pkgFile] {
+						seen[pkgFile] = true
+						added = append(added, pkgFile)
+						next = append(next, pkgFile)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+
+	if err := relativePaths(added); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// relativePaths replaces the absolute paths inside the working directory with paths
+// relative to it.
+func relativePaths(paths []string) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	for i, path := range paths {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			paths[i] = rel
+		}
+	}
+	return nil
+}
+
+// module returns the module containing dir, or nil.
+func (r *Resolver) module(dir string) (*Module, error) {
+	if m, ok := r.modules[dir]; ok {
+		return m, nil
+	}
+	m, err := FindModule(dir)
+	if err != nil {
+		return nil, err
+	}
+	if r.modules == nil {
+		r.modules = make(map[string]*Module)
+	}
+	r.modules[dir] = m
+	return m, nil
+}
+
+// packageDir returns the directory of the package imported as importPath from a
+// file in module m, or false if the package is not resolved.
+func (r *Resolver) packageDir(m *Module, importPath string) (string, bool) {
+	if m.Path != "" && (importPath == m.Path || strings.HasPrefix(importPath, m.Path+"/")) {
+		return filepath.Join(m.Dir, filepath.FromSlash(strings.TrimPrefix(importPath, m.Path))), true
+	}
+	if r.Vendor {
+		if dir := filepath.Join(m.Dir, "vendor", filepath.FromSlash(importPath)); isDir(dir) {
+			return dir, true
+		}
+	}
+	path, version, ok := m.requirement(importPath)
+	if !ok {
+		return "", false
+	}
+	sub := filepath.FromSlash(strings.TrimPrefix(importPath, path))
+	replacement, replaced := m.replacement(path, version)
+	if replaced && replacement.Dir != "" {
+		// A module replaced by a directory is followed like the module itself.
+		if dir := filepath.Join(replacement.Dir, sub); isDir(dir) {
+			return dir, true
+		}
+		return "", false
+	}
+	if cache := modCacheDir(); r.ModCache && cache != "" {
+		if replaced {
+			path, version = replacement.Path, replacement.Version
+		}
+		root := filepath.Join(cache, filepath.FromSlash(escapePath(path))+"@"+escapePath(version))
+		if dir := filepath.Join(root, sub); isDir(dir) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// parseImports returns the import paths of a Go file. Files that cannot be parsed
+// have no imports.
+func parseImports(file string) []string {
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+	imports := make([]string, 0, len(f.Imports))
+	for _, spec := range f.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, path)
+		}
+	}
+	return imports
+}
+
+// packageFiles returns the absolute paths of the non-test Go files in dir that
+// are built for the current platform.
+func packageFiles(dir string) []string {
+	pkg, err := build.Default.ImportDir(dir, 0)
+	if pkg == nil || (err != nil && len(pkg.GoFiles) == 0) {
+		return nil
+	}
+	files := make([]string, 0, len(pkg.GoFiles)+len(pkg.CgoFiles))
+	for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+		files = append(files, filepath.Join(dir, name))
+	}
+	return files
+}
+
+// modCacheDir returns the module cache directory, $GOMODCACHE or $GOPATH/pkg/mod,
+// or an empty string if neither is known.
+func modCacheDir() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(build.Default.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
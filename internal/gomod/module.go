@@ -0,0 +1,154 @@
+// Package gomod resolves the imports of Go files to the files of the imported
+// packages, within a module, its vendor directory or the module cache, and finds
+// the files of a module that refer to a symbol.
+package gomod
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Module is a Go module found on disk.
+type Module struct {
+	Path string // Module path from the module directive, e.g. "github.com/Jawkx/ctxcat"
+	Dir  string // Absolute directory containing go.mod
+
+	// Requires maps the paths of required modules to their versions.
+	Requires map[string]string
+	// Replaces maps "path@version", or "path" for all versions, to the module that
+	// replaces it.
+	Replaces map[string]Replacement
+}
+
+// Replacement is the target of a replace directive: a directory on disk, or another
+// module version.
+type Replacement struct {
+	Dir     string // Absolute directory of a replacement given as a file path
+	Path    string
+	Version string
+}
+
+// FindModule returns the module containing dir, found by looking for a go.mod
+// file in dir and its parents. It returns nil if dir is not inside a module.
+func FindModule(dir string) (*Module, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			return parseGoMod(dir, data), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// parseGoMod reads the module path, requirements and replacements from the contents
+// of a go.mod file. Directives that do not affect import resolution are ignored.
+func parseGoMod(dir string, data []byte) *Module {
+	m := &Module{Dir: dir, Requires: make(map[string]string), Replaces: make(map[string]Replacement)}
+	block := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			fields = append([]string{block}, fields...)
+		}
+		switch {
+		case (fields[0] == "require" || fields[0] == "replace") && len(fields) == 2 && fields[1] == "(":
+			block = fields[0]
+		case fields[0] == "module" && len(fields) >= 2:
+			m.Path = unquote(fields[1])
+		case fields[0] == "require" && len(fields) >= 3:
+			m.Requires[unquote(fields[1])] = fields[2]
+		case fields[0] == "replace":
+			m.parseReplace(fields[1:])
+		}
+	}
+	return m
+}
+
+// parseReplace records a replacement given as "old [version] => new [version]".
+func (m *Module) parseReplace(fields []string) {
+	arrow := slices.Index(fields, "=>")
+	if arrow < 1 || arrow > 2 || len(fields)-arrow < 2 || len(fields)-arrow > 3 {
+		return
+	}
+	key := unquote(fields[0])
+	if arrow == 2 {
+		key += "@" + fields[1]
+	}
+	target := unquote(fields[arrow+1])
+	if len(fields)-arrow == 3 {
+		m.Replaces[key] = Replacement{Path: target, Version: fields[arrow+2]}
+		return
+	}
+	// A target without a version is a directory, relative to the go.mod file.
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(m.Dir, filepath.FromSlash(target))
+	}
+	m.Replaces[key] = Replacement{Dir: target}
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, "\"`")
+}
+
+// requirement returns the required module that provides importPath, preferring
+// the longest matching module path.
+func (m *Module) requirement(importPath string) (path, version string, ok bool) {
+	for candidate, v := range m.Requires {
+		if (importPath == candidate || strings.HasPrefix(importPath, candidate+"/")) && len(candidate) > len(path) {
+			path, version, ok = candidate, v, true
+		}
+	}
+	return path, version, ok
+}
+
+// replacement returns the replacement of a required module version, if any.
+func (m *Module) replacement(path, version string) (Replacement, bool) {
+	if r, ok := m.Replaces[path+"@"+version]; ok {
+		return r, true
+	}
+	r, ok := m.Replaces[path]
+	return r, ok
+}
+
+// escapePath encodes a module path for the module cache, where every upper-case
+// letter is written as "!" followed by the lower-case letter.
+func escapePath(path string) string {
+	var sb strings.Builder
+	for _, r := range path {
+		if 'A' <= r && r <= 'Z' {
+			sb.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,424 @@
+package processor
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// commentSyntax describes the comments and string literals of a language, which
+// is all StripComments needs to know to remove comments without touching strings.
+type commentSyntax struct {
+	line  []string    // Line comment prefixes
+	block [][2]string // Block comment start and end delimiters
+	// Quote characters of string literals that end at the end of the line,
+	// with backslash escapes
+	quotes string
+	// Delimiters of string literals that can span lines, with backslash escapes
+	// unless they are listed in raw
+	multiline []string
+	raw       []string
+	// Line comments only start at the beginning of a word, as in shell scripts
+	// where "#" can appear inside words.
+	wordStart bool
+	// Here-documents: a match starts one, its non-empty group is the word that
+	// ends it, and the lines up to that word are kept as they are. They begin
+	// with "<<-" or "<<~" when the word can be indented.
+	heredoc *regexp.Regexp
+	// Rust: ' starts a character literal, such as 'x' or '\n', only when the
+	// literal is closed, and is otherwise a lifetime or a label; r"..." and
+	// r#"..."# are raw strings.
+	rust bool
+}
+
+var (
+	cSyntax = &commentSyntax{
+		line:   []string{"//"},
+		block:  [][2]string{{"/*", "*/"}},
+		quotes: `"'`,
+	}
+	goSyntax = &commentSyntax{
+		line:      []string{"//"},
+		block:     [][2]string{{"/*", "*/"}},
+		quotes:    `"'`,
+		multiline: []string{"`"},
+		raw:       []string{"`"},
+	}
+	jsSyntax = &commentSyntax{
+		line:      []string{"//"},
+		block:     [][2]string{{"/*", "*/"}},
+		quotes:    `"'`,
+		multiline: []string{"`"},
+	}
+	rustSyntax = &commentSyntax{
+		line:      []string{"//"},
+		block:     [][2]string{{"/*", "*/"}},
+		multiline: []string{`"`},
+		rust:      true,
+	}
+	cssSyntax = &commentSyntax{
+		block:  [][2]string{{"/*", "*/"}},
+		quotes: `"'`,
+	}
+	scssSyntax = &commentSyntax{
+		line:   []string{"//"},
+		block:  [][2]string{{"/*", "*/"}},
+		quotes: `"'`,
+	}
+	pythonSyntax = &commentSyntax{
+		line:      []string{"#"},
+		quotes:    `"'`,
+		multiline: []string{`"""`, `'''`},
+	}
+	hashSyntax = &commentSyntax{
+		line:      []string{"#"},
+		quotes:    `"'`,
+		wordStart: true,
+	}
+	// Shells allow a space before the word of a here-document, as in "cat << EOF".
+	shellSyntax = &commentSyntax{
+		line:      []string{"#"},
+		quotes:    `"'`,
+		wordStart: true,
+		heredoc:   regexp.MustCompile(`^<<-?[ \t]*(?:'([A-Za-z_]\w*)'|"([A-Za-z_]\w*)"|\\?([A-Za-z_]\w*))`),
+	}
+	// Ruby, Perl, Terraform and Dockerfiles write it right after "<<", as in "<<~EOS",
+	// which tells it apart from the "<<" operator.
+	scriptSyntax = &commentSyntax{
+		line:      []string{"#"},
+		quotes:    `"'`,
+		wordStart: true,
+		heredoc:   regexp.MustCompile(`^<<[-~]?(?:'([A-Za-z_]\w*)'|"([A-Za-z_]\w*)"|([A-Za-z_]\w*))`),
+	}
+	phpSyntax = &commentSyntax{
+		line:   []string{"//", "#"},
+		block:  [][2]string{{"/*", "*/"}},
+		quotes: `"'`,
+	}
+	sqlSyntax = &commentSyntax{
+		line:   []string{"--"},
+		block:  [][2]string{{"/*", "*/"}},
+		quotes: `"'`,
+	}
+	luaSyntax = &commentSyntax{
+		line:   []string{"--"},
+		block:  [][2]string{{"--[[", "]]"}},
+		quotes: `"'`,
+	}
+	haskellSyntax = &commentSyntax{
+		line:   []string{"--"},
+		block:  [][2]string{{"{-", "-}"}},
+		quotes: `"`,
+	}
+	// Markup text is full of apostrophes, so quotes are not tracked.
+	htmlSyntax = &commentSyntax{
+		block: [][2]string{{"<!--", "-->"}},
+	}
+	iniSyntax = &commentSyntax{
+		line:      []string{";", "#"},
+		wordStart: true,
+	}
+)
+
+// commentSyntaxes maps file extensions to the comment syntax of their language.
+var commentSyntaxes = map[string]*commentSyntax{
+	"go":    goSyntax,
+	"c":     cSyntax,
+	"h":     cSyntax,
+	"cc":    cSyntax,
+	"cpp":   cSyntax,
+	"cxx":   cSyntax,
+	"hpp":   cSyntax,
+	"m":     cSyntax,
+	"java":  cSyntax,
+	"cs":    cSyntax,
+	"kt":    cSyntax,
+	"kts":   cSyntax,
+	"scala": cSyntax,
+	"swift": cSyntax,
+	"dart":  cSyntax,
+	"proto": cSyntax,
+	"js":    jsSyntax,
+	"jsx":   jsSyntax,
+	"mjs":   jsSyntax,
+	"cjs":   jsSyntax,
+	"ts":    jsSyntax,
+	"tsx":   jsSyntax,
+	"rs":    rustSyntax,
+	"css":   cssSyntax,
+	"scss":  scssSyntax,
+	"less":  scssSyntax,
+	"php":   phpSyntax,
+	"py":    pythonSyntax,
+	"pyi":   pythonSyntax,
+	"sh":    shellSyntax,
+	"bash":  shellSyntax,
+	"zsh":   shellSyntax,
+	"fish":  hashSyntax,
+	"rb":    scriptSyntax,
+	"pl":    scriptSyntax,
+	"r":     hashSyntax,
+	"yaml":  hashSyntax,
+	"yml":   hashSyntax,
+	"toml":  hashSyntax,
+	"tf":    scriptSyntax,
+	"sql":   sqlSyntax,
+	"lua":   luaSyntax,
+	"hs":    haskellSyntax,
+	"html":  htmlSyntax,
+	"htm":   htmlSyntax,
+	"xml":   htmlSyntax,
+	"svg":   htmlSyntax,
+	"vue":   htmlSyntax,
+	"md":    htmlSyntax,
+	"ini":   iniSyntax,
+	"cfg":   iniSyntax,
+	"conf":  iniSyntax,
+}
+
+// commentSyntaxNames maps file names without a meaningful extension to their comment syntax.
+var commentSyntaxNames = map[string]*commentSyntax{
+	"Makefile":   hashSyntax,
+	"Dockerfile": scriptSyntax,
+	".gitignore": hashSyntax,
+	".env":       hashSyntax,
+}
+
+// StripComments removes comments from the file's content, keeping string literals
+// intact. Lines that only held a comment are removed; the remaining lines keep
+// their original line numbers. Files in unknown languages are left unchanged.
+func StripComments(file *File) {
+	syntax := commentSyntaxNames[file.Basename]
+	if syntax == nil {
+		syntax = commentSyntaxes[strings.ToLower(file.Extension)]
+	}
+	if syntax == nil {
+		return
+	}
+	file.SetLines(syntax.strip(file.Lines()))
+}
+
+// CollapseBlankLines replaces every run of blank lines in the file's content with a
+// single blank line.
+func CollapseBlankLines(file *File) {
+	var result []Line
+	previousBlank := false
+	for _, line := range file.Lines() {
+		blank := line.Omitted == 0 && strings.TrimSpace(line.Text) == ""
+		if blank && previousBlank {
+			continue
+		}
+		result = append(result, line)
+		previousBlank = blank
+	}
+	file.SetLines(result)
+}
+
+// strip removes the comments from lines.
+func (s *commentSyntax) strip(lines []Line) []Line {
+	var (
+		result []Line
+		// The delimiter that ends the block comment or multiline string being
+		// scanned at the end of the previous line.
+		blockEnd, stringEnd string
+		// Whether backslash escapes apply in that string.
+		stringEscapes bool
+		// The here-documents started on previous lines, in the order of their bodies.
+		heredocs []heredocEnd
+	)
+
+	for n, line := range lines {
+		if line.Omitted > 0 {
+			result = append(result, line)
+			continue
+		}
+		text := line.Text
+		if len(heredocs) > 0 {
+			result = append(result, line)
+			if end := heredocs[0]; text == end.word || end.indented && strings.TrimSpace(text) == end.word {
+				heredocs = heredocs[1:]
+			}
+			continue
+		}
+		// Keep the interpreter line of scripts.
+		if n == 0 && line.Number == 1 && strings.HasPrefix(text, "#!") {
+			result = append(result, line)
+			continue
+		}
+
+		var out strings.Builder
+		hadComment := false
+		i := 0
+
+		switch {
+		case blockEnd != "":
+			hadComment = true
+			end := strings.Index(text, blockEnd)
+			if end < 0 {
+				i = len(text)
+			} else {
+				i = end + len(blockEnd)
+				blockEnd = ""
+			}
+		case stringEnd != "":
+			end, closed := scanString(text, 0, stringEnd, stringEscapes)
+			out.WriteString(text[:end])
+			i = end
+			if closed {
+				stringEnd = ""
+			}
+		}
+
+	scan:
+		for i < len(text) {
+			for _, block := range s.block {
+				if strings.HasPrefix(text[i:], block[0]) {
+					hadComment = true
+					end := strings.Index(text[i+len(block[0]):], block[1])
+					if end < 0 {
+						blockEnd = block[1]
+						break scan
+					}
+					i += len(block[0]) + end + len(block[1])
+					continue scan
+				}
+			}
+			for _, prefix := range s.line {
+				if strings.HasPrefix(text[i:], prefix) && (!s.wordStart || i == 0 || text[i-1] == ' ' || text[i-1] == '\t') {
+					hadComment = true
+					break scan
+				}
+			}
+			if s.heredoc != nil && text[i] == '<' && (i == 0 || text[i-1] != '<') {
+				if m := s.heredoc.FindStringSubmatch(text[i:]); m != nil {
+					heredocs = append(heredocs, heredocEnd{
+						word:     m[1] + m[2] + m[3],
+						indented: m[0][2] == '-' || m[0][2] == '~',
+					})
+					out.WriteString(m[0])
+					i += len(m[0])
+					continue scan
+				}
+			}
+			if s.rust {
+				if open, delim := rustRawString(text, i); open > 0 {
+					end, closed := scanString(text, i+open, delim, false)
+					out.WriteString(text[i:end])
+					i = end
+					if !closed {
+						stringEnd, stringEscapes = delim, false
+					}
+					continue scan
+				}
+				if text[i] == '\'' {
+					if end := rustChar(text, i); end > 0 {
+						out.WriteString(text[i:end])
+						i = end
+						continue scan
+					}
+				}
+			}
+			for _, delim := range s.multiline {
+				if strings.HasPrefix(text[i:], delim) {
+					escapes := !slices.Contains(s.raw, delim)
+					end, closed := scanString(text, i+len(delim), delim, escapes)
+					out.WriteString(text[i:end])
+					i = end
+					if !closed {
+						stringEnd, stringEscapes = delim, escapes
+					}
+					continue scan
+				}
+			}
+			if strings.IndexByte(s.quotes, text[i]) >= 0 {
+				end, _ := scanString(text, i+1, text[i:i+1], true)
+				out.WriteString(text[i:end])
+				i = end
+				continue scan
+			}
+			out.WriteByte(text[i])
+			i++
+		}
+
+		stripped := out.String()
+		if hadComment {
+			stripped = strings.TrimRight(stripped, " \t")
+			if strings.TrimSpace(stripped) == "" && stringEnd == "" {
+				continue
+			}
+		}
+		result = append(result, Line{Number: line.Number, Text: stripped})
+	}
+	return result
+}
+
+// heredocEnd is the line that ends a here-document.
+type heredocEnd struct {
+	word     string
+	indented bool // The word can be preceded by whitespace
+}
+
+// scanString finds the end of a string literal in text that starts at i, just after
+// its opening delimiter. It returns the index after the closing delimiter, or the
+// end of text and false if the string is not closed on this line.
+func scanString(text string, i int, delim string, escapes bool) (int, bool) {
+	for i < len(text) {
+		if escapes && text[i] == '\\' {
+			i += 2
+			continue
+		}
+		if strings.HasPrefix(text[i:], delim) {
+			return i + len(delim), true
+		}
+		i++
+	}
+	return len(text), false
+}
+
+// rustRawString returns the length of the opening of the Rust raw string that
+// starts at text[i], such as r" or br#", and the delimiter that closes it, such as
+// "#. It returns 0 if no raw string starts there.
+func rustRawString(text string, i int) (int, string) {
+	if i > 0 && isIdentByte(text[i-1]) {
+		return 0, ""
+	}
+	j := i
+	if j < len(text) && text[j] == 'b' {
+		j++
+	}
+	if j >= len(text) || text[j] != 'r' {
+		return 0, ""
+	}
+	j++
+	hashes := 0
+	for j < len(text) && text[j] == '#' {
+		hashes++
+		j++
+	}
+	if j >= len(text) || text[j] != '"' {
+		return 0, ""
+	}
+	return j + 1 - i, `"` + strings.Repeat("#", hashes)
+}
+
+// rustChar returns the index after the Rust character literal that starts at
+// text[i], such as 'x', '"' or an escaped quote, or 0 if the quote starts a
+// lifetime or a label, such as 'a or 'outer.
+func rustChar(text string, i int) int {
+	if i+1 < len(text) && text[i+1] == '\\' {
+		if end, closed := scanString(text, i+1, "'", true); closed {
+			return end
+		}
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(text[i+1:])
+	if end := i + 1 + size; size > 0 && end < len(text) && text[end] == '\'' && text[i+1] != '\'' {
+		return end + 1
+	}
+	return 0
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
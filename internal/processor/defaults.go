@@ -0,0 +1,143 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultIgnoreDirs are directory names that are skipped unless Config.NoDefaultIgnores
+// is set: version control internals, dependency caches and build output.
+var DefaultIgnoreDirs = []string{
+	".git",
+	".hg",
+	".svn",
+	"node_modules",
+	"bower_components",
+	"vendor",
+	"dist",
+	"__pycache__",
+	".venv",
+	".tox",
+	".mypy_cache",
+	".pytest_cache",
+	".next",
+	".nuxt",
+	".gradle",
+	".idea",
+}
+
+// DefaultIgnoreFiles are file name patterns that are skipped unless Config.NoDefaultIgnores
+// is set, mostly lockfiles that are large and carry no information for a reader.
+var DefaultIgnoreFiles = []string{
+	"package-lock.json",
+	"npm-shrinkwrap.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"bun.lockb",
+	"go.sum",
+	"Cargo.lock",
+	"Gemfile.lock",
+	"composer.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"uv.lock",
+	".DS_Store",
+}
+
+// GeneratedFiles are file name patterns of machine-generated files.
+var GeneratedFiles = []string{
+	"*.min.js",
+	"*.min.css",
+	"*.map",
+}
+
+// generatedHeader matches the markers that code generators put near the top of a file:
+// Go's "// Code generated ... DO NOT EDIT." line and the "@generated" tag.
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$|@generated\b`)
+
+// generatedHeaderLines is how many lines from the top of a file are searched for a
+// generated-code marker.
+const generatedHeaderLines = 10
+
+// defaultRejection checks path against the default ignores and the generated file
+// names. Only the part of path below the literal input path it was found under is
+// checked, so naming an ignored path explicitly still selects it.
+func (p *FileProcessor) defaultRejection(filePath string, isDir bool) *Rejection {
+	if p.config.NoDefaultIgnores {
+		return nil
+	}
+
+	for current, last := filepath.Clean(filePath), true; !p.isLiteral(current); current, last = filepath.Dir(current), false {
+		name := filepath.Base(current)
+		if !last || isDir {
+			for _, dir := range DefaultIgnoreDirs {
+				if name == dir {
+					return &Rejection{Path: filePath, Rule: RuleDefault, Detail: dir + "/"}
+				}
+			}
+			continue
+		}
+		if pattern := matchName(DefaultIgnoreFiles, name); pattern != "" {
+			return &Rejection{Path: filePath, Rule: RuleDefault, Detail: pattern}
+		}
+		if pattern := matchName(GeneratedFiles, name); pattern != "" {
+			return &Rejection{Path: filePath, Rule: RuleGenerated, Detail: pattern}
+		}
+	}
+	return nil
+}
+
+// isLiteral reports whether path is, or is above, the literal part of an input path.
+func (p *FileProcessor) isLiteral(current string) bool {
+	if current == "." || filepath.Dir(current) == current {
+		return true
+	}
+	_, ok := p.literals[current]
+	return ok
+}
+
+// generatedMarker returns the line marking the start of a file as generated, or an
+// empty string if there is none.
+func generatedMarker(head []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(head))
+	for i := 0; i < generatedHeaderLines && scanner.Scan(); i++ {
+		line := strings.TrimSpace(scanner.Text())
+		if generatedHeader.MatchString(line) {
+			return line
+		}
+	}
+	return ""
+}
+
+// literalPrefix returns the leading path elements of an input path that contain no
+// glob syntax, or "." if the first element already does.
+func literalPrefix(input string) string {
+	input = filepath.Clean(input)
+	elems := strings.Split(filepath.ToSlash(input), "/")
+	for i, elem := range elems {
+		if strings.ContainsAny(elem, "*?[{\\") {
+			if i == 0 {
+				return "."
+			}
+			if i == 1 && elems[0] == "" {
+				return string(filepath.Separator)
+			}
+			return filepath.FromSlash(strings.Join(elems[:i], "/"))
+		}
+	}
+	return input
+}
+
+// matchName returns the first pattern that matches a file name.
+func matchName(patterns []string, name string) string {
+	for _, pattern := range patterns {
+		if match, err := path.Match(pattern, name); err == nil && match {
+			return pattern
+		}
+	}
+	return ""
+}
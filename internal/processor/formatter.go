@@ -2,59 +2,97 @@ package processor
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
+	"text/template"
+
+	"github.com/Jawkx/ctxcat/internal/tokenizer"
 )
 
-// Formatter applies a template to a file's content and metadata.
-type Formatter struct {
-	template string
+// GoTemplateHeader marks a template as a Go text/template when it is the first line.
+const GoTemplateHeader = "{{/* go-template */}}"
+
+// FormatterConfig holds the options for rendering files through a template.
+type FormatterConfig struct {
+	Template   string
+	GoTemplate bool                // Parse Template with text/template instead of {placeholder} substitution
+	Tokenizer  tokenizer.Tokenizer // Used by the tokens template function
 }
 
-// NewFormatter creates a new formatter with a given template.
-func NewFormatter(template string) (*Formatter, error) {
-	return &Formatter{template: template}, nil
+// Formatter applies a template to a file's content and metadata.
+type Formatter struct {
+	template   string
+	goTemplate *template.Template
 }
 
-// Format reads a file and applies the loaded template.
-func (f *Formatter) Format(path string) (string, error) {
-	contentBytes, err := os.ReadFile(path)
-	if err != nil {
-		return "", fmt.Errorf("reading file %s: %w", path, err)
+// NewFormatter creates a new formatter from the given configuration.
+func NewFormatter(config *FormatterConfig) (*Formatter, error) {
+	text, isGoTemplate := detectGoTemplate(config.Template, config.GoTemplate)
+	if !isGoTemplate {
+		return &Formatter{template: text}, nil
 	}
-	content := string(contentBytes)
 
-	// Get relative path for output consistency
-	relPath, err := filepath.Rel(".", path)
-	if err != nil {
-		relPath = path // Fallback to original path
+	tok := config.Tokenizer
+	if tok == nil {
+		tok = tokenizer.Estimator{}
 	}
-	relPath = filepath.ToSlash(relPath) // Use forward slashes for consistency
-
-	absPath, err := filepath.Abs(path)
+	tmpl, err := template.New("ctxcat").Funcs(templateFuncs(tok)).Parse(text)
 	if err != nil {
-		absPath = path // Fallback to original path
+		return nil, fmt.Errorf("parsing template: %w", err)
 	}
+	return &Formatter{template: text, goTemplate: tmpl}, nil
+}
 
-	absPath = filepath.ToSlash(absPath)
+// detectGoTemplate strips a leading GoTemplateHeader line from text and reports
+// whether the text should be parsed as a Go template.
+func detectGoTemplate(text string, goTemplate bool) (string, bool) {
+	if !strings.HasPrefix(text, GoTemplateHeader) {
+		return text, goTemplate
+	}
+	text = strings.TrimPrefix(text, GoTemplateHeader)
+	return strings.TrimPrefix(strings.TrimPrefix(text, "\r"), "\n"), true
+}
 
-	base := filepath.Base(relPath)
-	ext := filepath.Ext(base)
-	filename := strings.TrimSuffix(base, ext)
-	if len(ext) > 0 {
-		ext = ext[1:] // Remove the leading dot
+// Render implements Renderer.
+func (f *Formatter) Render(file *File) (string, error) {
+	if f.goTemplate != nil {
+		var sb strings.Builder
+		if err := f.goTemplate.Execute(&sb, file); err != nil {
+			return "", fmt.Errorf("executing template for %s: %w", file.Path, err)
+		}
+		return sb.String(), nil
 	}
 
 	// Using strings.Replacer is efficient for multiple replacements.
 	replacer := strings.NewReplacer(
-		"{content}", content,
-		"{path}", relPath,
-		"{abspath}", absPath,
-		"{basename}", base,
-		"{filename}", filename,
-		"{extension}", ext,
+		"{content}", file.Content,
+		"{numbered_content}", file.NumberedContent,
+		"{path}", file.Path,
+		"{abspath}", file.AbsPath,
+		"{basename}", file.Basename,
+		"{filename}", file.Filename,
+		"{extension}", file.Extension,
+		"{diff}", file.Diff,
+		"{truncated}", strconv.FormatBool(file.Truncated),
+		"{original_lines}", strconv.Itoa(file.OriginalLines),
+		"{start_line}", strconv.Itoa(file.StartLine),
+		"{end_line}", strconv.Itoa(file.EndLine),
+		"{mode}", file.Mode,
 	)
 
 	return replacer.Replace(f.template), nil
 }
+
+// Join implements Renderer.
+func (f *Formatter) Join(records []string) string {
+	var sb strings.Builder
+	for i, record := range records {
+		sb.WriteString(record)
+		// Add a newline between files if the template doesn't end with one
+		// and it's not the last file.
+		if len(record) > 0 && record[len(record)-1] != '\n' && i < len(records)-1 {
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
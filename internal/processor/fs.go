@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// FileSystem is the source of the files being processed. Paths use the operating
+// system's conventions and are relative to the working directory or absolute.
+type FileSystem interface {
+	Open(path string) (fs.File, error)
+	Stat(path string) (fs.FileInfo, error)
+	ReadFile(path string) ([]byte, error)
+	WalkDir(root string, fn fs.WalkDirFunc) error
+	Glob(pattern string) ([]string, error)
+}
+
+// osFileSystem reads files from the working tree on disk.
+type osFileSystem struct{}
+
+func (osFileSystem) Open(path string) (fs.File, error) {
+	return os.Open(path)
+}
+
+func (osFileSystem) Stat(path string) (fs.FileInfo, error) {
+	return os.Stat(path)
+}
+
+func (osFileSystem) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (osFileSystem) WalkDir(root string, fn fs.WalkDirFunc) error {
+	return filepath.WalkDir(root, fn)
+}
+
+func (osFileSystem) Glob(pattern string) ([]string, error) {
+	return doublestar.FilepathGlob(pattern)
+}
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/Jawkx/ctxcat/internal/tokenizer"
+)
+
+// templateFuncs returns the helper functions available to Go templates.
+func templateFuncs(tok tokenizer.Tokenizer) template.FuncMap {
+	return template.FuncMap{
+		"indent":    indent,
+		"escapeXML": xmlEscaper.Replace,
+		"lines":     lines,
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"trim":      strings.TrimSpace,
+		"lang":      lang,
+		"tokens":    tok.Count,
+	}
+}
+
+// indent prefixes every non-empty line of s with n spaces.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	ls := strings.SplitAfter(s, "\n")
+	for i, l := range ls {
+		if strings.TrimSpace(l) != "" {
+			ls[i] = pad + l
+		}
+	}
+	return strings.Join(ls, "")
+}
+
+// lines splits s into lines without their terminators.
+func lines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// languages maps file extensions to Markdown code fence language identifiers.
+var languages = map[string]string{
+	"bash":       "bash",
+	"c":          "c",
+	"cc":         "cpp",
+	"cpp":        "cpp",
+	"cs":         "csharp",
+	"css":        "css",
+	"dart":       "dart",
+	"dockerfile": "dockerfile",
+	"ex":         "elixir",
+	"exs":        "elixir",
+	"go":         "go",
+	"h":          "c",
+	"hpp":        "cpp",
+	"html":       "html",
+	"java":       "java",
+	"js":         "javascript",
+	"json":       "json",
+	"jsx":        "jsx",
+	"kt":         "kotlin",
+	"lua":        "lua",
+	"md":         "markdown",
+	"mjs":        "javascript",
+	"php":        "php",
+	"proto":      "protobuf",
+	"py":         "python",
+	"rb":         "ruby",
+	"rs":         "rust",
+	"scala":      "scala",
+	"scss":       "scss",
+	"sh":         "bash",
+	"sql":        "sql",
+	"swift":      "swift",
+	"toml":       "toml",
+	"ts":         "typescript",
+	"tsx":        "tsx",
+	"txt":        "text",
+	"xml":        "xml",
+	"yaml":       "yaml",
+	"yml":        "yaml",
+	"zig":        "zig",
+	"zsh":        "bash",
+}
+
+// lang returns the code fence language for a file extension, or an empty string if unknown.
+func lang(ext string) string {
+	return languages[strings.ToLower(strings.TrimPrefix(ext, "."))]
+}
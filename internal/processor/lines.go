@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Line is one line of a file's content, numbered from 1 as in the original file.
+// A run of lines left out of the output is represented by a single Line with
+// Omitted set to the number of lines in the run.
+type Line struct {
+	Number  int
+	Text    string
+	Omitted int
+}
+
+// SplitLines splits content into numbered lines. A final newline does not start a new line.
+func SplitLines(content string) []Line {
+	if content == "" {
+		return nil
+	}
+	texts := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	lines := make([]Line, len(texts))
+	for i, text := range texts {
+		lines[i] = Line{Number: i + 1, Text: text}
+	}
+	return lines
+}
+
+// JoinLines joins lines back into content, writing omitted runs as OmittedMarker.
+func JoinLines(lines []Line) string {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text
+		if line.Omitted > 0 {
+			texts[i] = OmittedMarker(line.Omitted)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// OmittedMarker is the line written in place of n lines that were left out.
+func OmittedMarker(n int) string {
+	return fmt.Sprintf("... [%d lines omitted] ...", n)
+}
+
+// GrepContext keeps the lines matching re and up to context lines around each match.
+// The lines in between are replaced by omitted runs.
+func GrepContext(lines []Line, re *regexp.Regexp, context int) []Line {
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if line.Omitted > 0 || !re.MatchString(line.Text) {
+			continue
+		}
+		for j := max(0, i-context); j <= min(len(lines)-1, i+context); j++ {
+			keep[j] = true
+		}
+	}
+	return elide(lines, keep)
+}
+
+// HeadLines keeps the first n lines and replaces the rest with a single omitted run.
+func HeadLines(lines []Line, n int) []Line {
+	keep := make([]bool, len(lines))
+	for i := range keep {
+		keep[i] = i < n
+	}
+	return elide(lines, keep)
+}
+
+// elide replaces every run of lines that are not kept with a single omitted run.
+func elide(lines []Line, keep []bool) []Line {
+	var result []Line
+	for i := 0; i < len(lines); {
+		if keep[i] {
+			result = append(result, lines[i])
+			i++
+			continue
+		}
+		run := Line{Number: lines[i].Number}
+		for ; i < len(lines) && !keep[i]; i++ {
+			run.Omitted += max(lines[i].Omitted, 1)
+		}
+		result = append(result, run)
+	}
+	return result
+}
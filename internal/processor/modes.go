@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Rendering modes that decide how much of a file is output.
+const (
+	ModeFull      = "full"      // The whole content, without outlining or per-file truncation
+	ModeOutline   = "outline"   // The outline of the content, see Outline
+	ModeTruncated = "truncated" // The content, truncated to the per-file limits
+	ModePathOnly  = "path-only" // No content, only the file's metadata
+)
+
+// Modes lists the rendering modes accepted by ParseModeRule.
+var Modes = []string{ModeFull, ModeOutline, ModeTruncated, ModePathOnly}
+
+// ModeRule assigns a rendering mode to the files matching a glob pattern.
+type ModeRule struct {
+	Glob string
+	Mode string // One of Modes
+}
+
+// ParseModeRule parses a rule of the form "glob=mode", e.g. "**/*_test.go=path-only".
+func ParseModeRule(s string) (ModeRule, error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return ModeRule{}, fmt.Errorf("invalid mode rule %q (expected glob=mode)", s)
+	}
+	rule := ModeRule{Glob: s[:i], Mode: strings.TrimSpace(s[i+1:])}
+	if !slices.Contains(Modes, rule.Mode) {
+		return ModeRule{}, fmt.Errorf("unknown mode %q in rule %q (expected one of: %s)", rule.Mode, s, strings.Join(Modes, ", "))
+	}
+	if !doublestar.ValidatePattern(filepath.ToSlash(rule.Glob)) {
+		return ModeRule{}, fmt.Errorf("invalid glob pattern in mode rule %q", s)
+	}
+	return rule, nil
+}
+
+// ModeRules are evaluated in order; the first rule matching a file decides its mode.
+type ModeRules []ModeRule
+
+// Mode returns the mode of the first rule matching path, or an empty string if no
+// rule matches.
+func (rules ModeRules) Mode(path string) string {
+	for _, rule := range rules {
+		match, err := doublestar.PathMatch(filepath.ToSlash(rule.Glob), filepath.ToSlash(path))
+		if err == nil && match {
+			return rule.Mode
+		}
+	}
+	return ""
+}
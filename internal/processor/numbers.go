@@ -0,0 +1,51 @@
+package processor
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultLineNumberFormat is the prefix written before each line by LineNumbers.
+const DefaultLineNumberFormat = "{n} | "
+
+// LineNumbers prefixes every line of a file with its line number in the original file.
+type LineNumbers struct {
+	Format string // Prefix with {n} standing for the line number
+	Width  int    // Minimum width of the line number, or 0 to fit the largest number in the file
+}
+
+// Number returns the file's content with line numbers. Omitted runs of lines get
+// a blank prefix of the same width.
+func (n *LineNumbers) Number(file *File) string {
+	lines := file.Lines()
+	width := n.Width
+	if width == 0 && len(lines) > 0 {
+		width = len(strconv.Itoa(lines[len(lines)-1].Number))
+	}
+
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		if line.Omitted > 0 {
+			sb.WriteString(strings.ReplaceAll(n.Format, "{n}", strings.Repeat(" ", width)))
+			sb.WriteString(OmittedMarker(line.Omitted))
+			continue
+		}
+		sb.WriteString(strings.ReplaceAll(n.Format, "{n}", padLeft(strconv.Itoa(line.Number), width)))
+		sb.WriteString(line.Text)
+	}
+	if strings.HasSuffix(file.Content, "\n") && sb.Len() > 0 {
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// padLeft pads s with spaces to at least width characters.
+func padLeft(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return strings.Repeat(" ", width-len(s)) + s
+}
@@ -0,0 +1,148 @@
+package processor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Summarizer reduces the source code of one language to an outline of its
+// declarations, so that a model sees the API of a file without its implementation.
+type Summarizer interface {
+	// Summarize returns the outline of the file's content as a subset of its lines.
+	// Elided parts, such as function bodies, are omitted runs.
+	Summarize(file *File) ([]Line, error)
+}
+
+var summarizers = map[string]Summarizer{}
+
+// RegisterSummarizer makes a summarizer available for files with the given
+// extension, without the leading dot. It panics if the extension is taken.
+func RegisterSummarizer(extension string, s Summarizer) {
+	if _, exists := summarizers[extension]; exists {
+		panic(fmt.Sprintf("processor: summarizer for %q registered twice", extension))
+	}
+	summarizers[extension] = s
+}
+
+// LookupSummarizer returns the summarizer for a file extension, or nil if the
+// language has none.
+func LookupSummarizer(extension string) Summarizer {
+	return summarizers[strings.ToLower(extension)]
+}
+
+// SummarizerExtensions lists the extensions that have a summarizer, in alphabetical order.
+func SummarizerExtensions() []string {
+	extensions := make([]string, 0, len(summarizers))
+	for extension := range summarizers {
+		extensions = append(extensions, extension)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// Outline replaces the file's content with its outline. It reports whether the
+// file's language has a summarizer; files in other languages are left unchanged.
+// The content must not contain omitted runs yet.
+func Outline(file *File) (bool, error) {
+	s := LookupSummarizer(file.Extension)
+	if s == nil {
+		return false, nil
+	}
+	lines, err := s.Summarize(file)
+	if err != nil {
+		return true, err
+	}
+	file.SetLines(lines)
+	return true, nil
+}
+
+func init() {
+	RegisterSummarizer("go", goSummarizer{})
+}
+
+// goSummarizer outlines Go source with go/ast: the package clause, imports, type
+// declarations, and function and method signatures with their doc comments.
+// Function bodies, variables, constants and free-floating comments are elided.
+type goSummarizer struct{}
+
+func (goSummarizer) Summarize(file *File) ([]Line, error) {
+	lines := slices.Clone(file.Lines())
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file.Path, file.Content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make([]bool, len(lines))
+	// keepLines keeps the lines from start to end, given as token positions.
+	keepLines := func(start, end token.Pos) {
+		for i := fset.Position(start).Line - 1; i < fset.Position(end).Line && i < len(lines); i++ {
+			keep[i] = true
+		}
+	}
+
+	start := f.Package
+	if f.Doc != nil {
+		start = f.Doc.Pos()
+	}
+	keepLines(start, f.Name.End())
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok != token.IMPORT && decl.Tok != token.TYPE {
+				continue
+			}
+			start := decl.Pos()
+			if decl.Doc != nil {
+				start = decl.Doc.Pos()
+			}
+			keepLines(start, decl.End())
+		case *ast.FuncDecl:
+			start := decl.Pos()
+			if decl.Doc != nil {
+				start = decl.Doc.Pos()
+			}
+			if decl.Body == nil {
+				keepLines(start, decl.End())
+				continue
+			}
+			// The signature up to the opening brace, and the closing brace.
+			keepLines(start, decl.Body.Lbrace)
+			keepLines(decl.Body.Rbrace, decl.Body.Rbrace)
+			lbrace, rbrace := fset.Position(decl.Body.Lbrace), fset.Position(decl.Body.Rbrace)
+			if lbrace.Line == rbrace.Line && len(decl.Body.List) > 0 && lbrace.Line <= len(lines) {
+				// A body on one line is elided within the line.
+				line := &lines[lbrace.Line-1]
+				line.Text = line.Text[:lbrace.Column-1] + "{ ... }" + line.Text[rbrace.Column:]
+			}
+		}
+	}
+
+	keepBlankLines(lines, keep)
+	return elide(lines, keep), nil
+}
+
+// keepBlankLines keeps the blank lines between two kept lines, so that declarations
+// stay apart without a marker between every pair of them.
+func keepBlankLines(lines []Line, keep []bool) {
+	blank := func(i int) bool { return strings.TrimSpace(lines[i].Text) == "" }
+	for i := range lines {
+		if !blank(i) {
+			continue
+		}
+		before, after := i-1, i+1
+		for before >= 0 && blank(before) {
+			before--
+		}
+		for after < len(lines) && blank(after) {
+			after++
+		}
+		keep[i] = before >= 0 && after < len(lines) && keep[before] && keep[after]
+	}
+}
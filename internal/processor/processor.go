@@ -2,14 +2,18 @@ package processor
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"github.com/sabhiram/go-gitignore"
@@ -22,28 +26,111 @@ type Config struct {
 	IgnoreFiles   []string
 	ExcludeGlobs  []string
 	NoBinaryCheck bool
+
+	// IncludeGlobs and Extensions restrict the selection to files matching at least
+	// one of them. Both are ignored when empty.
+	IncludeGlobs []string
+	Extensions   []string
+
+	// File size limits in bytes and the maximum number of lines. Zero means no limit.
+	MaxFileSize int64
+	MinFileSize int64
+	MaxLines    int
+
+	// Only files modified after NewerThan and before OlderThan are included.
+	// The zero time means no limit.
+	NewerThan time.Time
+	OlderThan time.Time
+
+	// Grep, when set, restricts the selection to files with a line matching it.
+	// Files with a line matching GrepInvert are left out.
+	Grep       *regexp.Regexp
+	GrepInvert *regexp.Regexp
+
+	// NoDefaultIgnores disables the built-in DefaultIgnoreDirs, DefaultIgnoreFiles
+	// and generated file detection.
+	NoDefaultIgnores bool
+
+	// AllowSecretFiles disables the rejection of files matching SecretFiles.
+	AllowSecretFiles bool
+
+	// FS is where files are read from. It defaults to the working tree on disk.
+	FS FileSystem
+
+	// Lister, when set, enumerates files inside version-controlled directories
+	// instead of walking them and evaluating .gitignore files.
+	Lister FileLister
+}
+
+// FileLister enumerates files the way a version control system sees them.
+type FileLister interface {
+	// ListFiles returns the files under path (a directory or a single file) and the
+	// ignored paths under it mapped to the rule that ignores them. Returned paths
+	// are built on path. ok is false when path is not managed by the lister.
+	ListFiles(path string) (files []string, ignored map[string]string, ok bool, err error)
 }
 
 // FileProcessor walks paths and filters files based on configuration.
 type FileProcessor struct {
 	config *Config
+	fs     FileSystem
 
 	// Cache for compiled .gitignore files to avoid re-parsing. Maps directory -> matcher.
 	ignoreMatcherCache map[string]*ignore.GitIgnore
 
 	// A single compiled matcher for all custom --ignore-file patterns.
 	customIgnoreMatcher *ignore.GitIgnore
+	// The "file:line" origin of every pattern line in customIgnoreMatcher.
+	customIgnoreSources []string
+
+	// Paths left out of the selection by the last call to ProcessPaths.
+	rejected []Rejection
+
+	// The literal, glob-free prefixes of the paths given to ProcessPaths.
+	// The default ignores only apply below them.
+	literals map[string]struct{}
 
-	mu sync.Mutex // Protects the cache
+	// The line ranges selected for files given as "file:10-20" or "file#Symbol".
+	ranges map[string][]LineRange
+
+	mu sync.Mutex // Protects the cache and the rejected list
 }
 
+// Rejection records a path that was left out of the selection and the rule that rejected it.
+type Rejection struct {
+	Path   string
+	IsDir  bool
+	Rule   string // One of the Rule* constants
+	Detail string // The pattern or file responsible, e.g. "src/.gitignore:3: *.js"
+}
+
+// Rules that can reject a path, in order of precedence.
+const (
+	RuleExclude    = "exclude"
+	RuleIgnoreFile = "ignore-file"
+	RuleGitignore  = "gitignore"
+	RuleDefault    = "default"
+	RuleSecret     = "secret"
+	RuleInclude    = "include"
+	RuleSize       = "size"
+	RuleModTime    = "mtime"
+	RuleGenerated  = "generated"
+	RuleBinary     = "binary"
+	RuleLines      = "lines"
+	RuleGrep       = "grep"
+)
+
 // New creates a new FileProcessor.
 func New(config *Config) (*FileProcessor, error) {
 	p := &FileProcessor{
 		config:             config,
+		fs:                 config.FS,
 		ignoreMatcherCache: make(map[string]*ignore.GitIgnore),
 		mu:                 sync.Mutex{},
 	}
+	if p.fs == nil {
+		p.fs = osFileSystem{}
+	}
 
 	// Pre-compile custom ignore files, as they apply to all paths globally.
 	if len(config.IgnoreFiles) > 0 {
@@ -55,8 +142,9 @@ func New(config *Config) (*FileProcessor, error) {
 				continue
 			}
 			scanner := bufio.NewScanner(f)
-			for scanner.Scan() {
+			for lineNo := 1; scanner.Scan(); lineNo++ {
 				allPatterns = append(allPatterns, scanner.Text())
+				p.customIgnoreSources = append(p.customIgnoreSources, fmt.Sprintf("%s:%d", file, lineNo))
 			}
 			f.Close()
 		}
@@ -71,26 +159,61 @@ func New(config *Config) (*FileProcessor, error) {
 // ProcessPaths takes a list of initial paths/globs and returns a filtered list of files.
 func (p *FileProcessor) ProcessPaths(paths []string) ([]string, error) {
 	expandedPaths := make(map[string]struct{})
+	p.literals = make(map[string]struct{})
+	p.ranges = make(map[string][]LineRange)
+	wholeFiles := make(map[string]bool)
 	for _, path := range paths {
-		matches, err := doublestar.FilepathGlob(path)
+		var lineRange *LineRange
+		if _, err := p.fs.Stat(path); err != nil {
+			if path, lineRange, err = ParseLineRange(path); err != nil {
+				return nil, err
+			}
+		}
+		p.literals[literalPrefix(path)] = struct{}{}
+		matches, err := p.fs.Glob(path)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: invalid glob pattern '%s': %v\n", path, err)
 			continue
 		}
 		for _, match := range matches {
 			expandedPaths[match] = struct{}{}
+			key := filepath.Clean(match)
+			if lineRange == nil {
+				wholeFiles[key] = true
+			} else if info, err := p.fs.Stat(match); err == nil && info.IsDir() {
+				fmt.Fprintf(os.Stderr, "Warning: ignoring line range %s of directory %s\n", lineRange, match)
+			} else {
+				p.ranges[key] = append(p.ranges[key], *lineRange)
+			}
 		}
 	}
+	// A file that is also given without a range is included in full.
+	for path := range wholeFiles {
+		delete(p.ranges, path)
+	}
 
 	finalFiles := make(map[string]struct{})
 	var mu sync.Mutex
+	p.rejected = nil
 
 	for path := range expandedPaths {
-		info, err := os.Stat(path)
+		info, err := p.fs.Stat(path)
 		if err != nil {
 			continue
 		}
 
+		if p.config.Lister != nil {
+			files, ignored, ok, err := p.config.Lister.ListFiles(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not list files in %s: %v\n", path, err)
+			} else if ok {
+				for _, file := range p.filterListed(path, info.IsDir(), files, ignored) {
+					finalFiles[file] = struct{}{}
+				}
+				continue
+			}
+		}
+
 		if !info.IsDir() {
 			if p.shouldInclude(path) {
 				mu.Lock()
@@ -107,7 +230,7 @@ func (p *FileProcessor) ProcessPaths(paths []string) ([]string, error) {
 			// We let WalkDir start, but it won't go deep.
 		}
 
-		walkErr := filepath.WalkDir(path, func(currentPath string, d fs.DirEntry, err error) error {
+		walkErr := p.fs.WalkDir(path, func(currentPath string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return err
 			}
@@ -117,8 +240,11 @@ func (p *FileProcessor) ProcessPaths(paths []string) ([]string, error) {
 					return filepath.SkipDir
 				}
 				// To improve performance, skip directories that are ignored.
-				if currentPath != path && p.shouldSkipDir(currentPath) {
-					return filepath.SkipDir
+				if currentPath != path {
+					if rejection := p.shouldSkipDir(currentPath); rejection != nil {
+						p.reject(rejection, true)
+						return filepath.SkipDir
+					}
 				}
 				return nil
 			}
@@ -143,6 +269,103 @@ func (p *FileProcessor) ProcessPaths(paths []string) ([]string, error) {
 	return result, nil
 }
 
+// filterListed applies the non-gitignore rules to files returned by the lister for
+// root, which already excludes ignored files. Ignored paths are recorded as rejections.
+func (p *FileProcessor) filterListed(root string, isDir bool, files []string, ignored map[string]string) []string {
+	root = filepath.Clean(root)
+	for path, rule := range ignored {
+		if p.config.NoRecursive && filepath.Dir(path) != root {
+			continue
+		}
+		info, err := p.fs.Stat(path)
+		p.reject(&Rejection{Path: path, Rule: RuleGitignore, Detail: rule}, err == nil && info.IsDir())
+	}
+
+	skippedDirs := make(map[string]bool)
+
+	var result []string
+	for _, file := range files {
+		// Tracked files that were deleted from the working tree are still listed.
+		if _, err := p.fs.Stat(file); err != nil {
+			continue
+		}
+		if isDir {
+			dir := filepath.Dir(file)
+			if p.config.NoRecursive && dir != root {
+				continue
+			}
+			if p.listedDirSkipped(root, dir, skippedDirs) {
+				continue
+			}
+		}
+		if p.includeFile(file, false) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// listedDirSkipped reports whether dir, or any directory between root and dir, is
+// rejected by the exclude or ignore-file rules, as it would be during a walk.
+// Results are memoized in cache.
+func (p *FileProcessor) listedDirSkipped(root, dir string, cache map[string]bool) bool {
+	if dir == root || filepath.Dir(dir) == dir {
+		return false
+	}
+	if skipped, ok := cache[dir]; ok {
+		return skipped
+	}
+
+	skipped := p.listedDirSkipped(root, filepath.Dir(dir), cache)
+	if !skipped {
+		if rejection := p.matchRules(dir, true, false); rejection != nil {
+			p.reject(rejection, true)
+			skipped = true
+		}
+	}
+	cache[dir] = skipped
+	return skipped
+}
+
+// Ranges returns the line ranges selected for a file by the last call to ProcessPaths,
+// or nil if the whole file is selected.
+func (p *FileProcessor) Ranges(path string) []LineRange {
+	return p.ranges[filepath.Clean(path)]
+}
+
+// FilterFiles applies the selection rules to files found after ProcessPaths, such as
+// the dependencies of selected files, and returns the files that are kept. The files
+// are treated as if they had been given explicitly, and rejected files are added to
+// Rejected.
+func (p *FileProcessor) FilterFiles(files []string) []string {
+	var kept []string
+	for _, file := range files {
+		p.literals[filepath.Clean(file)] = struct{}{}
+		if p.shouldInclude(file) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// AddRanges selects lines of a file in addition to the ranges it already has. A file
+// without ranges is selected in full, so this restricts it to the given lines; it is
+// meant for files added with FilterFiles, or selected with ranges already.
+func (p *FileProcessor) AddRanges(path string, ranges []LineRange) {
+	key := filepath.Clean(path)
+	p.ranges[key] = append(p.ranges[key], ranges...)
+}
+
+// Config returns the configuration the processor was created with.
+func (p *FileProcessor) Config() *Config {
+	return p.config
+}
+
+// ReadFile reads a file from the processor's file system.
+func (p *FileProcessor) ReadFile(path string) ([]byte, error) {
+	return p.fs.ReadFile(path)
+}
+
 // getGitignoreMatcher finds or compiles a .gitignore file for a given directory.
 // It returns the matcher and a boolean indicating if one was found/created.
 func (p *FileProcessor) getGitignoreMatcher(dir string) (*ignore.GitIgnore, bool) {
@@ -154,29 +377,52 @@ func (p *FileProcessor) getGitignoreMatcher(dir string) (*ignore.GitIgnore, bool
 	}
 
 	gitignorePath := filepath.Join(dir, ".gitignore")
-	matcher, err := ignore.CompileIgnoreFile(gitignorePath)
+	content, err := p.fs.ReadFile(gitignorePath)
 	if err != nil {
 		p.ignoreMatcherCache[dir] = nil
 		return nil, false
 	}
+	matcher := ignore.CompileIgnoreLines(strings.Split(string(content), "\n")...)
 
 	p.ignoreMatcherCache[dir] = matcher
 	return matcher, true
 }
 
+// Rejected returns the paths left out by the last call to ProcessPaths, sorted by path.
+// Directories are reported once and their contents are not listed.
+func (p *FileProcessor) Rejected() []Rejection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rejected := append([]Rejection(nil), p.rejected...)
+	sort.Slice(rejected, func(i, j int) bool {
+		return rejected[i].Path < rejected[j].Path
+	})
+	return rejected
+}
+
+// reject records a path that was left out of the selection.
+func (p *FileProcessor) reject(r *Rejection, isDir bool) {
+	r.IsDir = isDir
+	p.mu.Lock()
+	p.rejected = append(p.rejected, *r)
+	p.mu.Unlock()
+}
+
 // isGitignored checks a path against the hierarchy of .gitignore files.
-func (p *FileProcessor) isGitignored(path string) bool {
+// It returns the matching .gitignore file and line, or an empty string if the path is not ignored.
+func (p *FileProcessor) isGitignored(path string) string {
 	if p.config.NoGitignore {
-		return false
+		return ""
 	}
 
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return false
+		return ""
 	}
 
 	currentDir := absPath
-	info, err := os.Stat(absPath)
+	info, err := p.fs.Stat(absPath)
 	if err == nil && !info.IsDir() {
 		currentDir = filepath.Dir(absPath)
 	}
@@ -187,8 +433,14 @@ func (p *FileProcessor) isGitignored(path string) bool {
 			if err != nil {
 				continue
 			}
-			if matcher.MatchesPath(filepath.ToSlash(relativePath)) {
-				return true
+			if matched, how := matcher.MatchesPathHow(filepath.ToSlash(relativePath)); matched {
+				gitignorePath := filepath.Join(currentDir, ".gitignore")
+				if cwd, err := os.Getwd(); err == nil {
+					if rel, err := filepath.Rel(cwd, gitignorePath); err == nil {
+						gitignorePath = rel
+					}
+				}
+				return fmt.Sprintf("%s:%d: %s", filepath.ToSlash(gitignorePath), how.LineNo, how.Line)
 			}
 		}
 
@@ -199,15 +451,22 @@ func (p *FileProcessor) isGitignored(path string) bool {
 		currentDir = parentDir
 	}
 
-	return false
+	return ""
 }
 
 // shouldSkipDir checks if a directory should be skipped during the walk.
 // This is a subset of shouldInclude, without the binary check.
-func (p *FileProcessor) shouldSkipDir(path string) bool {
+// It returns the rule that rejected the path, or nil if the path is kept.
+func (p *FileProcessor) shouldSkipDir(path string) *Rejection {
+	return p.matchRules(path, true, true)
+}
+
+// matchRules applies the path-based rules in order of precedence. The .gitignore
+// rules are skipped when checkGitignore is false because a lister already applied them.
+func (p *FileProcessor) matchRules(path string, isDir, checkGitignore bool) *Rejection {
 	// Precedence 1: --exclude flag
-	if p.isExcluded(path) {
-		return true
+	if pattern := p.isExcluded(path); pattern != "" {
+		return &Rejection{Path: path, Rule: RuleExclude, Detail: pattern}
 	}
 
 	// Precedence 2: --ignore-file files
@@ -216,57 +475,200 @@ func (p *FileProcessor) shouldSkipDir(path string) bool {
 		if err != nil {
 			relPath = path
 		}
-		if p.customIgnoreMatcher.MatchesPath(filepath.ToSlash(relPath)) {
-			return true
+		if matched, how := p.customIgnoreMatcher.MatchesPathHow(filepath.ToSlash(relPath)); matched {
+			return &Rejection{
+				Path:   path,
+				Rule:   RuleIgnoreFile,
+				Detail: fmt.Sprintf("%s: %s", p.customIgnoreSources[how.LineNo-1], how.Line),
+			}
 		}
 	}
 
 	// Precedence 3: .gitignore files
-	if p.isGitignored(path) {
-		return true
+	if checkGitignore {
+		if source := p.isGitignored(path); source != "" {
+			return &Rejection{Path: path, Rule: RuleGitignore, Detail: source}
+		}
 	}
 
-	return false
+	// Precedence 4: built-in default ignores and generated file names
+	return p.defaultRejection(path, isDir)
 }
 
 // shouldInclude applies all filtering logic in the correct order of precedence for a file.
+// Rejected files are recorded for Rejected.
 func (p *FileProcessor) shouldInclude(path string) bool {
-	if p.shouldSkipDir(path) {
-		return false
+	return p.includeFile(path, true)
+}
+
+// includeFile is shouldInclude with optional .gitignore evaluation.
+func (p *FileProcessor) includeFile(path string, checkGitignore bool) bool {
+	rejection := p.matchRules(path, false, checkGitignore)
+
+	// Precedence 5: files that hold credentials, even when named explicitly
+	if rejection == nil && !p.config.AllowSecretFiles {
+		if pattern := isSecretFile(filepath.Base(path)); pattern != "" {
+			rejection = &Rejection{Path: path, Rule: RuleSecret, Detail: pattern}
+		}
 	}
 
-	// Precedence 4: Binary file check
-	if !p.config.NoBinaryCheck && isBinary(path) {
-		return false
+	// Precedence 6: --include and --ext flags
+	if rejection == nil && !p.isIncluded(path) {
+		rejection = &Rejection{Path: path, Rule: RuleInclude, Detail: "matches no --include pattern or --ext"}
+	}
+
+	// Precedence 7: size and modification time
+	if rejection == nil {
+		rejection = p.checkFileInfo(path)
 	}
 
+	// Precedence 8: Generated file and binary file checks on the start of the file
+	if rejection == nil && (!p.config.NoDefaultIgnores || !p.config.NoBinaryCheck) {
+		head := p.readHead(path)
+		if !p.config.NoDefaultIgnores && !p.isLiteral(filepath.Clean(path)) {
+			if marker := generatedMarker(head); marker != "" {
+				rejection = &Rejection{Path: path, Rule: RuleGenerated, Detail: marker}
+			}
+		}
+		if rejection == nil && !p.config.NoBinaryCheck && slices.Contains(head, 0) {
+			rejection = &Rejection{Path: path, Rule: RuleBinary, Detail: "contains null bytes"}
+		}
+	}
+
+	// Precedence 9: line count and content matches, which need the whole file
+	if rejection == nil {
+		rejection = p.checkContent(path)
+	}
+
+	if rejection != nil {
+		p.reject(rejection, false)
+		return false
+	}
 	return true
 }
 
 // isExcluded checks if a path matches any of the --exclude glob patterns.
-func (p *FileProcessor) isExcluded(path string) bool {
+// It returns the first matching pattern, or an empty string if none match.
+func (p *FileProcessor) isExcluded(path string) string {
 	for _, pattern := range p.config.ExcludeGlobs {
+		match, err := doublestar.PathMatch(filepath.ToSlash(pattern), filepath.ToSlash(path))
+		if err == nil && match {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// isIncluded checks if a file matches any of the --include glob patterns or --ext
+// extensions. Every file is included when neither is set.
+func (p *FileProcessor) isIncluded(path string) bool {
+	if len(p.config.IncludeGlobs) == 0 && len(p.config.Extensions) == 0 {
+		return true
+	}
+	for _, pattern := range p.config.IncludeGlobs {
 		match, err := doublestar.PathMatch(filepath.ToSlash(pattern), filepath.ToSlash(path))
 		if err == nil && match {
 			return true
 		}
 	}
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	for _, want := range p.config.Extensions {
+		if ext != "" && strings.EqualFold(ext, strings.TrimPrefix(want, ".")) {
+			return true
+		}
+	}
 	return false
 }
 
-// isBinary checks for null bytes in the first chunk of a file.
-func isBinary(path string) bool {
-	file, err := os.Open(path)
+// checkFileInfo applies the size and modification time limits to a file.
+// It returns the rule that rejected the file, or nil if the file is kept.
+func (p *FileProcessor) checkFileInfo(path string) *Rejection {
+	c := p.config
+	if c.MaxFileSize == 0 && c.MinFileSize == 0 && c.NewerThan.IsZero() && c.OlderThan.IsZero() {
+		return nil
+	}
+	info, err := p.fs.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	const timeFormat = "2006-01-02 15:04:05"
+	switch size, modTime := info.Size(), info.ModTime(); {
+	case c.MaxFileSize > 0 && size > c.MaxFileSize:
+		return &Rejection{Path: path, Rule: RuleSize, Detail: fmt.Sprintf("%d bytes > --max-file-size %d", size, c.MaxFileSize)}
+	case size < c.MinFileSize:
+		return &Rejection{Path: path, Rule: RuleSize, Detail: fmt.Sprintf("%d bytes < --min-file-size %d", size, c.MinFileSize)}
+	case !c.NewerThan.IsZero() && !modTime.After(c.NewerThan):
+		return &Rejection{Path: path, Rule: RuleModTime, Detail: fmt.Sprintf(
+			"modified %s, not after --newer-than %s", modTime.Format(timeFormat), c.NewerThan.Format(timeFormat))}
+	case !c.OlderThan.IsZero() && !modTime.Before(c.OlderThan):
+		return &Rejection{Path: path, Rule: RuleModTime, Detail: fmt.Sprintf(
+			"modified %s, not before --older-than %s", modTime.Format(timeFormat), c.OlderThan.Format(timeFormat))}
+	}
+	return nil
+}
+
+// checkContent applies the line count limit and the --grep and --grep-v patterns to a file.
+// It returns the rule that rejected the file, or nil if the file is kept.
+func (p *FileProcessor) checkContent(path string) *Rejection {
+	c := p.config
+	if c.MaxLines == 0 && c.Grep == nil && c.GrepInvert == nil {
+		return nil
+	}
+	content, err := p.fs.ReadFile(path)
 	if err != nil {
-		return false
+		return nil
+	}
+
+	if c.MaxLines > 0 {
+		if lines := CountLines(content); lines > c.MaxLines {
+			return &Rejection{Path: path, Rule: RuleLines, Detail: fmt.Sprintf("%d lines > --max-lines %d", lines, c.MaxLines)}
+		}
+	}
+	if c.Grep != nil && matchLine(c.Grep, content) == 0 {
+		return &Rejection{Path: path, Rule: RuleGrep, Detail: fmt.Sprintf("no line matches --grep %s", c.Grep)}
+	}
+	if c.GrepInvert != nil {
+		if line := matchLine(c.GrepInvert, content); line > 0 {
+			return &Rejection{Path: path, Rule: RuleGrep, Detail: fmt.Sprintf("line %d matches --grep-v %s", line, c.GrepInvert)}
+		}
+	}
+	return nil
+}
+
+// matchLine returns the number of the first line of content that matches re, or 0 if none does.
+func matchLine(re *regexp.Regexp, content []byte) int {
+	for i, line := range bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n")) {
+		if re.Match(line) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// CountLines returns the number of lines in content, counting a final line without a newline.
+func CountLines(content []byte) int {
+	n := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		n++
+	}
+	return n
+}
+
+// readHead reads the first chunk of a file, which is enough to detect binary and
+// generated files. It returns nil if the file cannot be read.
+func (p *FileProcessor) readHead(path string) []byte {
+	file, err := p.fs.Open(path)
+	if err != nil {
+		return nil
 	}
 	defer file.Close()
 
 	buffer := make([]byte, 1024)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return false
+	n, err := io.ReadFull(file, buffer)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil
 	}
 
-	return slices.Contains(buffer[:n], 0)
+	return buffer[:n]
 }
@@ -0,0 +1,319 @@
+package processor
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineRange selects part of a file, either by line numbers or by the name of a
+// symbol defined in it.
+type LineRange struct {
+	Start, End int    // 1-based and inclusive. End is 0 for the end of the file.
+	Symbol     string // Function, type or method name, e.g. "Server.Run"; resolved against the content
+}
+
+func (r LineRange) String() string {
+	if r.Symbol != "" {
+		return "#" + r.Symbol
+	}
+	if r.End == 0 {
+		return fmt.Sprintf(":%d-", r.Start)
+	}
+	return fmt.Sprintf(":%d-%d", r.Start, r.End)
+}
+
+var (
+	lineRangeSuffix = regexp.MustCompile(`^(.+):(\d+)(?:-(\d*))?$`)
+	symbolSuffix    = regexp.MustCompile(`^(.+)#([\pL_][\pL\pN_]*(?:\.[\pL_][\pL\pN_]*)?)$`)
+)
+
+// ParseLineRange splits an input path of the form "file:120-180", "file:120",
+// "file:120-" or "file#Symbol" into the path and the range. Paths without a range
+// suffix are returned unchanged with a nil range.
+func ParseLineRange(input string) (string, *LineRange, error) {
+	if m := symbolSuffix.FindStringSubmatch(input); m != nil {
+		return m[1], &LineRange{Symbol: m[2]}, nil
+	}
+	m := lineRangeSuffix.FindStringSubmatch(input)
+	if m == nil {
+		return input, nil, nil
+	}
+
+	start, _ := strconv.Atoi(m[2])
+	end := start
+	if strings.Contains(input[len(m[1]):], "-") {
+		end = 0
+		if m[3] != "" {
+			end, _ = strconv.Atoi(m[3])
+		}
+	}
+	if start < 1 || (end != 0 && end < start) {
+		return "", nil, fmt.Errorf("invalid line range in %q", input)
+	}
+	return m[1], &LineRange{Start: start, End: end}, nil
+}
+
+// ResolveSymbols returns ranges with the symbols replaced by the lines of their
+// definitions in the file. Symbols must be resolved before a transform changes the
+// content, since their lines are found by parsing it.
+func ResolveSymbols(file *File, ranges []LineRange) ([]LineRange, error) {
+	var resolved []LineRange
+	for _, r := range ranges {
+		if r.Symbol != "" {
+			start, end, err := findSymbol(file, r.Symbol)
+			if err != nil {
+				return nil, err
+			}
+			r = LineRange{Start: start, End: end}
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved, nil
+}
+
+// SelectRanges replaces the file's content with the lines in ranges. Runs of lines
+// between two ranges are omitted; lines before the first and after the last range
+// are left out without a marker.
+//
+// Lines are selected by their number in the original file, so the content may
+// already have been transformed. A line that stands for several lines of the
+// original, such as a redacted multi-line secret, is kept if any of them is in a
+// range.
+func SelectRanges(file *File, ranges []LineRange) error {
+	ranges, err := ResolveSymbols(file, ranges)
+	if err != nil {
+		return err
+	}
+	total := file.OriginalLines
+	first, last := total+1, 0
+	for i, r := range ranges {
+		if r.End == 0 || r.End > total {
+			ranges[i].End = total
+		}
+		if r.Start > total {
+			return fmt.Errorf("line range %s is past the end of %s (%d lines)", r, file.Path, total)
+		}
+		first, last = min(first, r.Start), max(last, ranges[i].End)
+	}
+	if last < first {
+		return nil
+	}
+
+	lines := file.Lines()
+	var selected []Line
+	var keep []bool
+	for i, line := range lines {
+		// The original lines that the line stands for.
+		from, to := line.Number, line.Number+max(line.Omitted, 1)-1
+		if i+1 < len(lines) {
+			to = lines[i+1].Number - 1
+		} else {
+			to = max(to, total)
+		}
+		if to < first || from > last {
+			continue
+		}
+		kept := false
+		for _, r := range ranges {
+			kept = kept || (from <= r.End && to >= r.Start)
+		}
+		if !kept {
+			line = Line{Number: from, Omitted: to - from + 1}
+		}
+		selected = append(selected, line)
+		keep = append(keep, kept)
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	file.SetLines(elide(selected, keep))
+	file.StartLine, file.EndLine = selected[0].Number, last
+	return nil
+}
+
+// findSymbol returns the lines of the definition of symbol in the file, including
+// its doc comment.
+func findSymbol(file *File, symbol string) (int, int, error) {
+	var start, end int
+	if file.Extension == "go" {
+		start, end = findGoSymbol(file.Content, symbol)
+	} else {
+		start, end = findSymbolHeuristic(file.Lines(), symbol)
+	}
+	if start == 0 {
+		return 0, 0, fmt.Errorf("symbol %s not found in %s", symbol, file.Path)
+	}
+	return start, end, nil
+}
+
+// findGoSymbol finds a function, method ("Type.Method"), type, constant or variable
+// in Go source. It returns zero if the source does not parse or the symbol is not found.
+func findGoSymbol(src, symbol string) (int, int) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return 0, 0
+	}
+	lines := func(node ast.Node, doc *ast.CommentGroup) (int, int) {
+		pos := node.Pos()
+		if doc != nil {
+			pos = doc.Pos()
+		}
+		return fset.Position(pos).Line, fset.Position(node.End()).Line
+	}
+
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if goFuncName(decl) == symbol {
+				return lines(decl, decl.Doc)
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				var names []*ast.Ident
+				var doc *ast.CommentGroup
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					names, doc = []*ast.Ident{spec.Name}, spec.Doc
+				case *ast.ValueSpec:
+					names, doc = spec.Names, spec.Doc
+				}
+				for _, name := range names {
+					if name.Name != symbol {
+						continue
+					}
+					// A declaration with a single spec includes its keyword.
+					if len(decl.Specs) == 1 {
+						return lines(decl, decl.Doc)
+					}
+					return lines(spec, doc)
+				}
+			}
+		}
+	}
+	return 0, 0
+}
+
+// goFuncName returns the name of a function, or "Type.Method" for a method.
+func goFuncName(decl *ast.FuncDecl) string {
+	if decl.Recv == nil || len(decl.Recv.List) == 0 {
+		return decl.Name.Name
+	}
+	recv := decl.Recv.List[0].Type
+	for {
+		switch t := recv.(type) {
+		case *ast.StarExpr:
+			recv = t.X
+			continue
+		case *ast.IndexExpr:
+			recv = t.X
+			continue
+		case *ast.IndexListExpr:
+			recv = t.X
+			continue
+		case *ast.Ident:
+			return t.Name + "." + decl.Name.Name
+		}
+		return decl.Name.Name
+	}
+}
+
+// definitionKeywords introduce a named definition in the languages findSymbolHeuristic knows.
+const definitionKeywords = `def|class|function|fn|struct|enum|trait|interface|type|impl|module|func|sub|procedure|const|let|var`
+
+// findSymbolHeuristic finds the definition of symbol in source code of any language:
+// a line with a definition keyword followed by the name, extended to its closing
+// brace, or to the end of its indented block when the line ends with a colon.
+// Comments and decorators directly above the definition are included.
+func findSymbolHeuristic(lines []Line, symbol string) (int, int) {
+	name := symbol
+	if i := strings.LastIndex(symbol, "."); i >= 0 {
+		name = symbol[i+1:]
+	}
+	definition := regexp.MustCompile(`\b(?:` + definitionKeywords + `)\s+(?:[\pL_][\pL\pN_]*\s+for\s+)?` +
+		regexp.QuoteMeta(name) + `\b`)
+
+	for i, line := range lines {
+		if !definition.MatchString(line.Text) {
+			continue
+		}
+		start := i
+		for start > 0 && isCommentOrDecorator(lines[start-1].Text) {
+			start--
+		}
+		return lines[start].Number, lines[blockEnd(lines, i)].Number
+	}
+	return 0, 0
+}
+
+// signatureLines is how many lines findSymbolHeuristic looks ahead for the opening
+// brace of a definition.
+const signatureLines = 5
+
+// blockEnd returns the index of the last line of the block starting at lines[i].
+func blockEnd(lines []Line, i int) int {
+	// Indented blocks, as in Python.
+	if strings.HasSuffix(strings.TrimSpace(lines[i].Text), ":") {
+		return indentedBlockEnd(lines, i, leadingSpace(lines[i].Text))
+	}
+
+	// Brace-delimited blocks, possibly opened on a later line of the signature.
+	depth, opened := 0, false
+	for j := i; j < len(lines); j++ {
+		for _, c := range lines[j].Text {
+			switch c {
+			case '{':
+				depth++
+				opened = true
+			case '}':
+				depth--
+			}
+		}
+		if opened && depth <= 0 {
+			return j
+		}
+		if !opened && (j-i >= signatureLines || strings.HasSuffix(strings.TrimSpace(lines[j].Text), ";")) {
+			break
+		}
+	}
+	return i
+}
+
+// indentedBlockEnd returns the index of the last line after lines[i] that is
+// indented deeper than indentation, before the first one that is not.
+func indentedBlockEnd(lines []Line, i, indentation int) int {
+	end := i
+	for j := i + 1; j < len(lines); j++ {
+		if strings.TrimSpace(lines[j].Text) == "" {
+			continue
+		}
+		if leadingSpace(lines[j].Text) <= indentation {
+			break
+		}
+		end = j
+	}
+	return end
+}
+
+// isCommentOrDecorator reports whether a line is a comment or an annotation that
+// belongs to the definition below it.
+func isCommentOrDecorator(text string) bool {
+	text = strings.TrimSpace(text)
+	for _, prefix := range []string{"//", "#", "/*", "*", "@", "--"} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// leadingSpace returns the width of the indentation of a line, counting a tab as one column.
+func leadingSpace(text string) int {
+	return len(text) - len(strings.TrimLeft(text, " \t"))
+}
@@ -0,0 +1,321 @@
+package processor
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SecretFiles are file name patterns of files that hold credentials. They are
+// rejected unless Config.AllowSecretFiles is set, even when named explicitly.
+var SecretFiles = []string{
+	".env",
+	".env.*",
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"*.pfx",
+	"*.jks",
+	"*.keystore",
+	"id_rsa",
+	"id_dsa",
+	"id_ecdsa",
+	"id_ed25519",
+	".netrc",
+	".pgpass",
+	".htpasswd",
+}
+
+// secretFileExamples are names matched by SecretFiles that are templates without
+// real values, and are not rejected.
+var secretFileExamples = []string{".env.example", ".env.sample", ".env.template", ".env.dist"}
+
+// isSecretFile returns the SecretFiles pattern matching the file name, or an empty string.
+func isSecretFile(name string) string {
+	for _, example := range secretFileExamples {
+		if name == example {
+			return ""
+		}
+	}
+	return matchName(SecretFiles, name)
+}
+
+// configFiles are file name patterns of configuration files, in which unquoted
+// values such as PASSWORD=hunter2 are redacted. Elsewhere an unquoted value is code,
+// as in password := req.Password, and only quoted string literals are redacted.
+var configFiles = []string{
+	".env", ".env.*", "*.env",
+	"*.ini", "*.cfg", "*.conf", "*.properties", "*.toml", "*.yaml", "*.yml",
+	".npmrc", ".pypirc", ".netrc", ".pgpass",
+}
+
+// isConfigFile reports whether the file at path is a configuration file.
+func isConfigFile(path string) bool {
+	return matchName(configFiles, filepath.Base(path)) != ""
+}
+
+// detector finds one kind of secret. When the pattern has capturing groups, only
+// the first group that matched is redacted.
+type detector struct {
+	name    string
+	pattern *regexp.Regexp
+	// valid, if set, filters out matches that are not secrets.
+	valid func(match string) bool
+	// config restricts the detector to configuration files.
+	config bool
+}
+
+// secretKey matches the names of settings that hold secrets.
+const secretKey = `[\w.-]*(?:password|passwd|secret|api_?key|access_?token|auth_?token)`
+
+// detectors are the built-in secret detectors, in order of precedence.
+var detectors = []*detector{
+	{
+		name:    "private-key",
+		pattern: regexp.MustCompile(`-----BEGIN[A-Z ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END[A-Z ]*PRIVATE KEY( BLOCK)?-----`),
+	},
+	{
+		name:    "aws-access-key",
+		pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`),
+	},
+	{
+		name:    "aws-secret-key",
+		pattern: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*(?::=|[:=])\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	},
+	{
+		name:    "github-token",
+		pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`),
+	},
+	{
+		name:    "jwt",
+		pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`),
+	},
+	{
+		// A string literal assigned to a secret setting, in any file.
+		name: "password",
+		pattern: regexp.MustCompile(`(?i)\b` + secretKey + `["']?\s*(?::=|=>|[:=])\s*` +
+			"(?:\"([^\"\\s]+)\"|'([^'\\s]+)'|`([^`\\s]+)`)"),
+		valid: isLiteralValue,
+	},
+	{
+		// An unquoted value of a secret setting, in configuration files only.
+		name:    "password",
+		pattern: regexp.MustCompile(`(?im)^[ \t]*(?:export[ \t]+)?` + secretKey + `[\w.-]*[ \t]*[:=][ \t]*([^\s"'#,;]+)`),
+		valid:   isLiteralValue,
+		config:  true,
+	},
+	{
+		name:    "high-entropy",
+		pattern: regexp.MustCompile("[\"'`]([A-Za-z0-9+/=_-]{32,})[\"'`]"),
+		valid:   isHighEntropy,
+	},
+}
+
+// selector matches a qualified identifier, such as settings.SECRET_KEY.
+var selector = regexp.MustCompile(`^[A-Za-z_]\w*(?:\.[A-Za-z_]\w*)+$`)
+
+// isLiteralValue reports whether the value of a setting looks like a literal secret
+// rather than a reference to one, such as ${PASSWORD} or settings.SECRET_KEY, or a
+// placeholder or type name, such as changeme or string.
+func isLiteralValue(value string) bool {
+	if len(value) < 4 || strings.ContainsAny(value, "()[]{}<>") || strings.HasPrefix(value, "$") ||
+		strings.HasPrefix(value, "%") || selector.MatchString(value) {
+		return false
+	}
+	switch strings.ToLower(value) {
+	case "null", "none", "true", "false", "undefined", "changeme", "required", "optional",
+		"string", "bool", "boolean", "number", "integer", "password", "secret":
+		return false
+	}
+	return true
+}
+
+// minEntropy is the Shannon entropy, in bits per character, above which a string
+// is considered random. Hex digests stay below it; random base64 is above.
+const minEntropy = 4.5
+
+// isHighEntropy reports whether s looks like a random token: it mixes letters and
+// digits and has a high Shannon entropy.
+func isHighEntropy(s string) bool {
+	if !strings.ContainsAny(s, "0123456789") || strings.ToLower(s) == s || strings.ToUpper(s) == s {
+		return false
+	}
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	entropy := 0.0
+	for _, n := range counts {
+		p := float64(n) / float64(len(s))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minEntropy
+}
+
+// Redactor replaces secrets in file contents with "[REDACTED:type]" markers.
+type Redactor struct {
+	detectors []*detector
+}
+
+// NewRedactor creates a redactor with the built-in detectors, unless builtin is
+// false, and a detector for each of the custom regular expressions.
+func NewRedactor(builtin bool, custom []string) (*Redactor, error) {
+	r := &Redactor{}
+	if builtin {
+		r.detectors = append(r.detectors, detectors...)
+	}
+	for _, expr := range custom {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", expr, err)
+		}
+		r.detectors = append(r.detectors, &detector{name: "custom", pattern: pattern})
+	}
+	return r, nil
+}
+
+// secretMatch is the location of a secret in a text.
+type secretMatch struct {
+	start, end int
+	name       string
+}
+
+// find returns the non-overlapping secrets in text, sorted by position. config
+// enables the detectors for configuration files.
+func (r *Redactor) find(text string, config bool) []secretMatch {
+	var matches []secretMatch
+	for _, d := range r.detectors {
+		if d.config && !config {
+			continue
+		}
+		for _, loc := range d.pattern.FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			for group := 2; group+1 < len(loc); group += 2 {
+				if loc[group] >= 0 {
+					start, end = loc[group], loc[group+1]
+					break
+				}
+			}
+			if d.valid != nil && !d.valid(text[start:end]) {
+				continue
+			}
+			matches = append(matches, secretMatch{start: start, end: end, name: d.name})
+		}
+	}
+
+	// Detectors earlier in the list win when matches overlap.
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	var result []secretMatch
+	for _, m := range matches {
+		if len(result) > 0 && m.start < result[len(result)-1].end {
+			continue
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// Redact replaces the secrets in text from the file at path, such as its diff, and
+// counts them by type in counts.
+func (r *Redactor) Redact(path, text string, counts map[string]int) string {
+	var sb strings.Builder
+	pos := 0
+	for _, m := range r.find(text, isConfigFile(path)) {
+		sb.WriteString(text[pos:m.start])
+		sb.WriteString(redactedMarker(m.name))
+		counts[m.name]++
+		pos = m.end
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+// Apply replaces the secrets in the file's content and counts them by type in
+// counts. A secret spanning several lines, such as a private key, is replaced on
+// its first line and the other lines are removed, so line numbers stay correct.
+// Omitted runs are kept as they are, and secrets are only searched for in the
+// lines between them.
+func (r *Redactor) Apply(file *File, counts map[string]int) {
+	lines := file.Lines()
+	config := isConfigFile(file.Path)
+	var result []Line
+	changed := false
+	for start := 0; start < len(lines); {
+		if lines[start].Omitted > 0 {
+			result = append(result, lines[start])
+			start++
+			continue
+		}
+		end := start
+		for end < len(lines) && lines[end].Omitted == 0 {
+			end++
+		}
+		redacted, ok := r.redactLines(lines[start:end], config, counts)
+		result = append(result, redacted...)
+		changed = changed || ok
+		start = end
+	}
+	if changed {
+		file.SetLines(result)
+	}
+}
+
+// redactLines replaces the secrets in a run of lines without omitted runs. It
+// returns false, and lines unchanged, if there are none.
+func (r *Redactor) redactLines(lines []Line, config bool, counts map[string]int) ([]Line, bool) {
+	text := JoinLines(lines)
+	matches := r.find(text, config)
+	if len(matches) == 0 {
+		return lines, false
+	}
+
+	var result []Line
+	var current strings.Builder
+	index := 0
+	number := lines[0].Number
+	copyText := func(s string) {
+		for _, c := range s {
+			if c != '\n' {
+				current.WriteRune(c)
+				continue
+			}
+			result = append(result, Line{Number: number, Text: current.String()})
+			current.Reset()
+			index++
+			number = lines[index].Number
+		}
+	}
+
+	pos := 0
+	for _, m := range matches {
+		copyText(text[pos:m.start])
+		current.WriteString(redactedMarker(m.name))
+		counts[m.name]++
+		index += strings.Count(text[m.start:m.end], "\n")
+		pos = m.end
+	}
+	copyText(text[pos:])
+	result = append(result, Line{Number: number, Text: current.String()})
+	return result, true
+}
+
+func redactedMarker(name string) string {
+	return "[REDACTED:" + name + "]"
+}
+
+// FormatCounts describes redaction counts as "2 aws-access-key, 1 jwt", sorted by type.
+func FormatCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%d %s", counts[name], name)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,138 @@
+package processor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// File holds a file's content together with the metadata exposed to templates
+// and structured output formats.
+type File struct {
+	Index     int    // 1-based position in the output
+	Path      string // Relative path with forward slashes
+	AbsPath   string
+	Basename  string // Filename with extension
+	Filename  string // Filename without extension
+	Extension string // Extension without the leading dot
+	Size      int    // Size of Content in bytes
+	Content   string
+	Diff      string // Unified diff of the file, when diff mode is enabled
+
+	NumberedContent string // Content with each line prefixed by its number in the original file
+
+	Truncated     bool // Whether lines were left out of Content
+	OriginalLines int  // Number of lines in the file before any were left out
+
+	// The first and last line of the file in Content. They differ from 1 and
+	// OriginalLines when a line range was selected.
+	StartLine int
+	EndLine   int
+
+	Mode string // The rendering mode assigned by a ModeRule, if any
+
+	// The numbered lines of Content, once it has been changed with SetLines.
+	lines []Line
+}
+
+// NewFile builds a File from a path and its content.
+func NewFile(index int, path, content string) *File {
+	// Get relative path for output consistency
+	relPath, err := filepath.Rel(".", path)
+	if err != nil {
+		relPath = path // Fallback to original path
+	}
+	relPath = filepath.ToSlash(relPath) // Use forward slashes for consistency
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path // Fallback to original path
+	}
+
+	absPath = filepath.ToSlash(absPath)
+
+	base := filepath.Base(relPath)
+	ext := filepath.Ext(base)
+	filename := strings.TrimSuffix(base, ext)
+	if len(ext) > 0 {
+		ext = ext[1:] // Remove the leading dot
+	}
+
+	lines := CountLines([]byte(content))
+	return &File{
+		Index:     index,
+		Path:      relPath,
+		AbsPath:   absPath,
+		Basename:  base,
+		Filename:  filename,
+		Extension: ext,
+		Size:      len(content),
+		Content:   content,
+
+		OriginalLines: lines,
+		StartLine:     min(1, lines),
+		EndLine:       lines,
+	}
+}
+
+// Lines returns the numbered lines of the file's content.
+func (f *File) Lines() []Line {
+	if f.lines != nil {
+		return f.lines
+	}
+	return SplitLines(f.Content)
+}
+
+// SetContent replaces the file's content, forgetting the line numbers of the old content.
+func (f *File) SetContent(content string) {
+	f.lines = nil
+	f.Content = content
+	f.Size = len(content)
+}
+
+// SetLines replaces the file's content with lines. A final newline is kept.
+// The file is marked as truncated if lines contains an omitted run.
+func (f *File) SetLines(lines []Line) {
+	content := JoinLines(lines)
+	if strings.HasSuffix(f.Content, "\n") && content != "" {
+		content += "\n"
+	}
+	f.lines = lines
+	f.Content = content
+	f.Size = len(content)
+	for _, line := range lines {
+		if line.Omitted > 0 {
+			f.Truncated = true
+		}
+	}
+}
+
+// Renderer turns files into the final output text.
+type Renderer interface {
+	// Render returns the output for a single file.
+	Render(file *File) (string, error)
+	// Join combines the rendered files into the complete output.
+	Join(records []string) string
+}
+
+// Formats lists the structured output formats accepted by NewRenderer.
+var Formats = []string{"json", "jsonl", "xml", "markdown"}
+
+// NewRenderer returns the renderer for an output format.
+// An empty format renders each file through the configured template.
+func NewRenderer(format string, config *FormatterConfig) (Renderer, error) {
+	switch format {
+	case "":
+		return NewFormatter(config)
+	case "json":
+		return jsonRenderer{}, nil
+	case "jsonl":
+		return jsonlRenderer{}, nil
+	case "xml":
+		return xmlRenderer{}, nil
+	case "markdown", "md":
+		return markdownRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (expected one of: %s)", format, strings.Join(Formats, ", "))
+	}
+}
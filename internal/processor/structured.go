@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonRecord is the shape of a file in the json and jsonl formats.
+type jsonRecord struct {
+	Index     int    `json:"index"`
+	Path      string `json:"path"`
+	AbsPath   string `json:"abspath"`
+	Basename  string `json:"basename"`
+	Filename  string `json:"filename"`
+	Extension string `json:"extension"`
+	Size      int    `json:"size"`
+	Content   string `json:"content"`
+	Diff      string `json:"diff,omitempty"`
+
+	// Only set for truncated files.
+	Truncated     bool `json:"truncated,omitempty"`
+	OriginalLines int  `json:"original_lines,omitempty"`
+
+	// Only set when a line range was selected.
+	StartLine int `json:"start_line,omitempty"`
+	EndLine   int `json:"end_line,omitempty"`
+
+	// Only set when a mode rule matched the file.
+	Mode string `json:"mode,omitempty"`
+}
+
+func marshalRecord(file *File) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Source code is full of <, > and &; escaping them only wastes tokens.
+	enc.SetEscapeHTML(false)
+	record := jsonRecord{
+		Index:     file.Index,
+		Path:      file.Path,
+		AbsPath:   file.AbsPath,
+		Basename:  file.Basename,
+		Filename:  file.Filename,
+		Extension: file.Extension,
+		Size:      file.Size,
+		Content:   file.Content,
+		Diff:      file.Diff,
+		Mode:      file.Mode,
+	}
+	if file.Truncated {
+		record.Truncated, record.OriginalLines = true, file.OriginalLines
+	}
+	if file.StartLine > 1 || file.EndLine < file.OriginalLines {
+		record.StartLine, record.EndLine = file.StartLine, file.EndLine
+	}
+	// Encoding a struct of strings and ints cannot fail.
+	_ = enc.Encode(record)
+	return buf.String()
+}
+
+// jsonRenderer emits a single JSON array of file records.
+type jsonRenderer struct{}
+
+func (jsonRenderer) Render(file *File) (string, error) {
+	return "  " + strings.TrimSuffix(marshalRecord(file), "\n"), nil
+}
+
+func (jsonRenderer) Join(records []string) string {
+	if len(records) == 0 {
+		return "[]\n"
+	}
+	return "[\n" + strings.Join(records, ",\n") + "\n]\n"
+}
+
+// jsonlRenderer emits one JSON record per line.
+type jsonlRenderer struct{}
+
+func (jsonlRenderer) Render(file *File) (string, error) {
+	return marshalRecord(file), nil
+}
+
+func (jsonlRenderer) Join(records []string) string {
+	return strings.Join(records, "")
+}
+
+// xmlRenderer emits the <documents> layout recommended for long-context prompts.
+type xmlRenderer struct{}
+
+var xmlEscaper = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	`"`, "&quot;",
+	"'", "&apos;",
+)
+
+func (xmlRenderer) Render(file *File) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<document index=\"%d\">\n<source>%s</source>\n", file.Index, xmlEscaper.Replace(file.Path))
+	fmt.Fprintf(&sb, "<document_content>\n%s\n</document_content>\n", xmlEscaper.Replace(file.Content))
+	if file.Diff != "" {
+		fmt.Fprintf(&sb, "<document_diff>\n%s\n</document_diff>\n", xmlEscaper.Replace(file.Diff))
+	}
+	sb.WriteString("</document>\n")
+	return sb.String(), nil
+}
+
+func (xmlRenderer) Join(records []string) string {
+	return "<documents>\n" + strings.Join(records, "") + "</documents>\n"
+}
+
+// markdownRenderer emits a heading and a fenced code block per file. The fence is
+// always longer than any run of backticks in the content so it cannot be closed early.
+type markdownRenderer struct{}
+
+func (markdownRenderer) Render(file *File) (string, error) {
+	out := fmt.Sprintf("## %s\n\n%s", file.Path, fenced(file.Extension, file.Content))
+	if file.Diff != "" {
+		out += "\n" + fenced("diff", file.Diff)
+	}
+	return out, nil
+}
+
+// fenced wraps content in a code fence that the content cannot close.
+func fenced(language, content string) string {
+	fence := strings.Repeat("`", max(3, longestRun(content, '`')+1))
+	return fmt.Sprintf("%s%s\n%s\n%s\n", fence, language, content, fence)
+}
+
+func (markdownRenderer) Join(records []string) string {
+	return strings.Join(records, "\n")
+}
+
+// longestRun returns the length of the longest run of c in s.
+func longestRun(s string, c byte) int {
+	longest, current := 0, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == c {
+			current++
+			longest = max(longest, current)
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
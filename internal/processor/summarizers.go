@@ -0,0 +1,257 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+)
+
+func init() {
+	RegisterSummarizer("py", pythonSummarizer{})
+	RegisterSummarizer("pyi", pythonSummarizer{})
+	for _, extension := range []string{"js", "jsx", "mjs", "cjs", "ts", "tsx", "mts", "cts"} {
+		RegisterSummarizer(extension, jsSummarizer)
+	}
+	RegisterSummarizer("rs", rustSummarizer)
+}
+
+// The heuristic summarizers below work on lines of text rather than a syntax tree,
+// so they need no parser for the language and accept code that does not compile.
+// Declarations are recognized by the keywords they start with, and their extent
+// by brace matching or indentation, as in findSymbolHeuristic.
+
+var (
+	pythonDefinition = regexp.MustCompile(`^\s*(?:async\s+)?def\s|^\s*class\s`)
+	pythonImport     = regexp.MustCompile(`^(?:import|from)\s`)
+	docstringStart   = regexp.MustCompile(`^[rRuUbBfF]{0,2}("""|''')`)
+)
+
+// pythonSummarizer outlines Python: imports, the module docstring, classes with
+// their attributes, and function signatures with their decorators and docstrings.
+// Function bodies and other top-level statements are elided.
+type pythonSummarizer struct{}
+
+func (pythonSummarizer) Summarize(file *File) ([]Line, error) {
+	lines := file.Lines()
+	keep := make([]bool, len(lines))
+	// The indentation of the members of the classes around the current line.
+	var classes []int
+
+	// The module docstring follows the interpreter line and comments, if any.
+	start := 0
+	for start < len(lines) && strings.HasPrefix(lines[start].Text, "#") {
+		start++
+	}
+
+	for i := keepDocstring(lines, keep, start) + 1; i < len(lines); i++ {
+		text := lines[i].Text
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		indentation := leadingSpace(text)
+		for len(classes) > 0 && indentation < classes[len(classes)-1] {
+			classes = classes[:len(classes)-1]
+		}
+
+		switch {
+		case pythonDefinition.MatchString(text):
+			keepComments(lines, keep, i)
+			// The signature ends where its brackets are balanced.
+			signature, depth := i, 0
+			for j := i; j < len(lines) && j-i <= signatureLines; j++ {
+				t := lines[j].Text
+				depth += strings.Count(t, "(") + strings.Count(t, "[") - strings.Count(t, ")") - strings.Count(t, "]")
+				if depth <= 0 {
+					signature = j
+					break
+				}
+			}
+			keepRange(keep, i, signature)
+			end := keepDocstring(lines, keep, signature+1)
+			if !strings.HasPrefix(strings.TrimSpace(text), "class") {
+				i = indentedBlockEnd(lines, signature, indentation)
+				continue
+			}
+			if next := nextNonBlank(lines, signature+1); next < len(lines) && leadingSpace(lines[next].Text) > indentation {
+				classes = append(classes, leadingSpace(lines[next].Text))
+			}
+			i = max(i, end)
+		case len(classes) == 0 && indentation == 0 && pythonImport.MatchString(text):
+			// Parenthesized imports can span lines.
+			end := i
+			if strings.Contains(text, "(") {
+				for end < len(lines)-1 && !strings.Contains(lines[end].Text, ")") {
+					end++
+				}
+			}
+			keepRange(keep, i, end)
+			i = end
+		case len(classes) > 0 && indentation == classes[len(classes)-1]:
+			// Class attributes, such as the fields of a dataclass.
+			keep[i] = true
+		}
+	}
+
+	keepBlankLines(lines, keep)
+	return elide(lines, keep), nil
+}
+
+// keepDocstring keeps the docstring starting at the first non-blank line at or after
+// from, if there is one. It returns the index of its last line, or from-1.
+func keepDocstring(lines []Line, keep []bool, from int) int {
+	start := nextNonBlank(lines, from)
+	if start >= len(lines) {
+		return from - 1
+	}
+	text := strings.TrimSpace(lines[start].Text)
+	m := docstringStart.FindStringSubmatch(text)
+	if m == nil {
+		return from - 1
+	}
+	end := start
+	if !strings.Contains(text[len(m[0]):], m[1]) {
+		for end < len(lines)-1 {
+			end++
+			if strings.Contains(lines[end].Text, m[1]) {
+				break
+			}
+		}
+	}
+	keepRange(keep, start, end)
+	return end
+}
+
+// declKind is how much of a declaration an outline keeps.
+type declKind int
+
+const (
+	// The whole declaration, e.g. imports, struct and interface types.
+	declWhole declKind = iota
+	// The signature and the closing brace, e.g. functions.
+	declSignature
+	// The opening and closing lines, with the members outlined, e.g. classes.
+	declContainer
+)
+
+// declRule recognizes a declaration by its first line.
+type declRule struct {
+	pattern *regexp.Regexp
+	kind    declKind
+	member  bool // Only applies directly inside a container
+}
+
+// braceSummarizer outlines languages with brace-delimited blocks.
+type braceSummarizer struct {
+	rules []declRule
+}
+
+var jsSummarizer = braceSummarizer{rules: []declRule{
+	{pattern: regexp.MustCompile(`^\s*import\b|^\s*export\s+(?:type\s+)?(?:\*|\{)`), kind: declWhole},
+	{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:interface|type|enum|const\s+enum)\s+[\w$]`), kind: declWhole},
+	{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?class\b`), kind: declContainer},
+	{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:namespace|module)\s+[\w$."']`), kind: declContainer},
+	{pattern: regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:async\s+)?function\b`), kind: declSignature},
+	{pattern: regexp.MustCompile(`^\s*export\s+(?:default\s+)?(?:const|let|var)\s+[\w$]`), kind: declSignature},
+	// Methods and fields of classes.
+	{
+		pattern: regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|readonly|abstract|override|async|get|set|declare)\s+)*` +
+			`\*?\s*(?:#?[\w$]+|\[[^\]]+\])\??\s*(?:<[^>]*>)?\s*[(:=;]`),
+		kind:   declSignature,
+		member: true,
+	},
+}}
+
+const rustVisibility = `^\s*(?:pub(?:\([^)]*\))?\s+)?`
+
+var rustSummarizer = braceSummarizer{rules: []declRule{
+	{pattern: regexp.MustCompile(rustVisibility + `(?:default\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"[^"]*"\s+)?fn\s`), kind: declSignature},
+	{pattern: regexp.MustCompile(rustVisibility + `(?:use\s|extern\s+crate\s|mod\s+\w+\s*;)`), kind: declWhole},
+	{pattern: regexp.MustCompile(rustVisibility + `(?:struct|enum|union|type|const|static)\s`), kind: declWhole},
+	{pattern: regexp.MustCompile(rustVisibility + `(?:unsafe\s+)?(?:impl|trait|mod)\b`), kind: declContainer},
+	{pattern: regexp.MustCompile(`^\s*macro_rules!`), kind: declSignature},
+}}
+
+func (s braceSummarizer) Summarize(file *File) ([]Line, error) {
+	lines := file.Lines()
+	keep := make([]bool, len(lines))
+	// The last lines of the containers around the current line.
+	var containers []int
+
+	for i := 0; i < len(lines); i++ {
+		for len(containers) > 0 && i > containers[len(containers)-1] {
+			containers = containers[:len(containers)-1]
+		}
+		rule := s.match(lines[i].Text, len(containers) > 0)
+		if rule == nil {
+			continue
+		}
+
+		keepComments(lines, keep, i)
+		end := blockEnd(lines, i)
+		switch rule.kind {
+		case declWhole:
+			keepRange(keep, i, end)
+			i = end
+		case declSignature:
+			keepRange(keep, i, openingLine(lines, i, end))
+			keep[end] = true
+			i = end
+		case declContainer:
+			open := openingLine(lines, i, end)
+			keepRange(keep, i, open)
+			keep[end] = true
+			if end > open {
+				containers = append(containers, end)
+			}
+			i = open
+		}
+	}
+
+	keepBlankLines(lines, keep)
+	return elide(lines, keep), nil
+}
+
+// match returns the first rule that recognizes a declaration on the line, or nil.
+func (s braceSummarizer) match(text string, inContainer bool) *declRule {
+	for i, rule := range s.rules {
+		if rule.member && !inContainer {
+			continue
+		}
+		if rule.pattern.MatchString(text) {
+			return &s.rules[i]
+		}
+	}
+	return nil
+}
+
+// openingLine returns the index of the line that opens the block of the
+// declaration spanning lines i to end, or end if it has no block.
+func openingLine(lines []Line, i, end int) int {
+	for j := i; j < end; j++ {
+		if strings.Contains(lines[j].Text, "{") {
+			return j
+		}
+	}
+	return end
+}
+
+// keepComments keeps the comments and decorators directly above lines[i].
+func keepComments(lines []Line, keep []bool, i int) {
+	for j := i - 1; j >= 0 && isCommentOrDecorator(lines[j].Text); j-- {
+		keep[j] = true
+	}
+}
+
+// keepRange keeps the lines from start to end, inclusive.
+func keepRange(keep []bool, start, end int) {
+	for i := start; i <= end; i++ {
+		keep[i] = true
+	}
+}
+
+// nextNonBlank returns the index of the first non-blank line at or after from, or len(lines).
+func nextNonBlank(lines []Line, from int) int {
+	for from < len(lines) && strings.TrimSpace(lines[from].Text) == "" {
+		from++
+	}
+	return from
+}
@@ -0,0 +1,54 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Jawkx/ctxcat/internal/tokenizer"
+)
+
+// Summary holds the aggregate values available to header and footer templates.
+type Summary struct {
+	FileCount   int
+	TotalBytes  int
+	TotalTokens int
+	Date        string
+	GitBranch   string
+	Tree        string
+}
+
+// RenderSummary applies a header or footer template to the summary. Templates use
+// {placeholder} substitution unless goTemplate is set or they start with GoTemplateHeader.
+func RenderSummary(text string, goTemplate bool, tok tokenizer.Tokenizer, summary *Summary) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	text, goTemplate = detectGoTemplate(text, goTemplate)
+	if goTemplate {
+		if tok == nil {
+			tok = tokenizer.Estimator{}
+		}
+		tmpl, err := template.New("summary").Funcs(templateFuncs(tok)).Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("parsing template: %w", err)
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, summary); err != nil {
+			return "", fmt.Errorf("executing template: %w", err)
+		}
+		return sb.String(), nil
+	}
+
+	replacer := strings.NewReplacer(
+		"{file_count}", strconv.Itoa(summary.FileCount),
+		"{total_bytes}", strconv.Itoa(summary.TotalBytes),
+		"{total_tokens}", strconv.Itoa(summary.TotalTokens),
+		"{date}", summary.Date,
+		"{git_branch}", summary.GitBranch,
+		"{tree}", summary.Tree,
+	)
+	return replacer.Replace(text), nil
+}
@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TruncateModes lists the parts of a file that Truncation can keep.
+var TruncateModes = []string{"head", "tail", "middle"}
+
+// DefaultTruncateLines is the line limit of files in the truncated rendering mode
+// when no per-file limit is set.
+const DefaultTruncateLines = 100
+
+// Truncation limits the size of each file, replacing the lines it leaves out with
+// an omitted run.
+type Truncation struct {
+	MaxLines int    // Maximum number of lines kept, or 0 for no limit
+	MaxBytes int    // Maximum number of bytes kept, or 0 for no limit. Only whole lines are kept.
+	Mode     string // One of TruncateModes: keep the start, the end, or both ends of the file
+}
+
+// NewTruncation validates the truncation mode and returns the truncation.
+func NewTruncation(maxLines, maxBytes int, mode string) (*Truncation, error) {
+	if !slices.Contains(TruncateModes, mode) {
+		return nil, fmt.Errorf("unknown truncate mode %q (expected one of: %s)", mode, strings.Join(TruncateModes, ", "))
+	}
+	return &Truncation{MaxLines: maxLines, MaxBytes: maxBytes, Mode: mode}, nil
+}
+
+// Apply truncates the file if it is over the limits.
+func (t *Truncation) Apply(file *File) {
+	lines := file.Lines()
+	keep := make([]bool, len(lines))
+	for i := range keep {
+		keep[i] = true
+	}
+
+	truncated := false
+	if t.MaxLines > 0 && len(lines) > t.MaxLines {
+		t.limit(keep, func(int) int { return 1 }, t.MaxLines)
+		truncated = true
+	}
+	if t.MaxBytes > 0 && len(file.Content) > t.MaxBytes {
+		// Each line is counted with its newline.
+		t.limit(keep, func(i int) int { return len(lines[i].Text) + 1 }, t.MaxBytes)
+		truncated = true
+	}
+	if truncated {
+		file.SetLines(elide(lines, keep))
+	}
+}
+
+// limit unmarks lines in keep until the sizes of the kept lines add up to at most
+// limit, keeping lines from the start, the end, or both ends depending on the mode.
+func (t *Truncation) limit(keep []bool, size func(int) int, limit int) {
+	var kept []int
+	for i := range keep {
+		if keep[i] {
+			kept = append(kept, i)
+		}
+	}
+
+	headLimit, tailLimit := limit, 0
+	switch t.Mode {
+	case "tail":
+		headLimit, tailLimit = 0, limit
+	case "middle":
+		headLimit = (limit + 1) / 2
+		tailLimit = limit - headLimit
+	}
+
+	for _, i := range kept {
+		keep[i] = false
+	}
+	head := 0
+	for _, i := range kept {
+		if headLimit < size(i) {
+			break
+		}
+		headLimit -= size(i)
+		keep[i] = true
+		head++
+	}
+	for j := len(kept) - 1; j >= head; j-- {
+		i := kept[j]
+		if tailLimit < size(i) {
+			break
+		}
+		tailLimit -= size(i)
+		keep[i] = true
+	}
+}
@@ -0,0 +1,140 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// The vocabularies in vocab/ are embedded into the binary. genvocab.go downloads them.
+//
+//go:generate go run genvocab.go
+//go:embed vocab
+var embeddedVocab embed.FS
+
+// BPE is a byte-level byte-pair encoder compatible with tiktoken rank files.
+type BPE struct {
+	name  string
+	ranks map[string]int
+	split pretokenizer
+}
+
+// LoadBPE loads the "<name>_base.tiktoken" vocabulary, preferring the copy embedded
+// in the binary and falling back to $CTXCAT_TOKENIZER_DIR and ~/.cache/ctxcat.
+func LoadBPE(name string) (*BPE, error) {
+	fileName := name + "_base.tiktoken"
+
+	if data, err := embeddedVocab.ReadFile("vocab/" + fileName); err == nil {
+		return NewBPE(name, bytes.NewReader(data))
+	}
+
+	var searchDirs []string
+	if dir := os.Getenv("CTXCAT_TOKENIZER_DIR"); dir != "" {
+		searchDirs = append(searchDirs, dir)
+	}
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		searchDirs = append(searchDirs, filepath.Join(cacheDir, "ctxcat"))
+	}
+
+	for _, dir := range searchDirs {
+		f, err := os.Open(filepath.Join(dir, fileName))
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		return NewBPE(name, f)
+	}
+
+	return nil, fmt.Errorf(
+		"vocabulary %s not found; build ctxcat after running go generate ./internal/tokenizer, "+
+			"place it in $CTXCAT_TOKENIZER_DIR or use --tokenizer estimate",
+		fileName,
+	)
+}
+
+// NewBPE builds an encoder from a tiktoken rank file, where every line holds a
+// base64-encoded token followed by its rank. name selects the pre-tokenizer the
+// vocabulary was trained with, "cl100k" or "o200k".
+func NewBPE(name string, r io.Reader) (*BPE, error) {
+	split, ok := pretokenizers[name]
+	if !ok {
+		return nil, fmt.Errorf("no pre-tokenizer for vocabulary %s", name)
+	}
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		fields := bytes.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("vocabulary %s line %d: expected \"<token> <rank>\"", name, lineNo)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary %s line %d: %w", name, lineNo, err)
+		}
+		rank, err := strconv.Atoi(string(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("vocabulary %s line %d: %w", name, lineNo, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading vocabulary %s: %w", name, err)
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocabulary %s is empty", name)
+	}
+	return &BPE{name: name, ranks: ranks, split: split}, nil
+}
+
+// Name implements Tokenizer.
+func (b *BPE) Name() string {
+	return b.name
+}
+
+// Count implements Tokenizer.
+func (b *BPE) Count(text string) int {
+	count := 0
+	for _, piece := range splitPieces(text, b.split) {
+		count += b.countPiece(piece)
+	}
+	return count
+}
+
+// countPiece returns the number of tokens a single pre-tokenized piece encodes to.
+func (b *BPE) countPiece(piece string) int {
+	if _, ok := b.ranks[piece]; ok {
+		return 1
+	}
+
+	// parts holds the start offset of every current token, plus a sentinel at len(piece).
+	parts := make([]int, len(piece)+1)
+	for i := range parts {
+		parts[i] = i
+	}
+
+	for len(parts) > 2 {
+		minRank, minIdx := math.MaxInt, -1
+		for i := 0; i < len(parts)-2; i++ {
+			if rank, ok := b.ranks[piece[parts[i]:parts[i+2]]]; ok && rank < minRank {
+				minRank, minIdx = rank, i
+			}
+		}
+		if minIdx < 0 {
+			break
+		}
+		parts = append(parts[:minIdx+1], parts[minIdx+2:]...)
+	}
+
+	return len(parts) - 1
+}
@@ -0,0 +1,145 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitPieces(t *testing.T) {
+	testCases := []struct {
+		name   string
+		text   string
+		cl100k []string
+		o200k  []string
+	}{
+		{
+			name:   "words",
+			text:   "Hello world",
+			cl100k: []string{"Hello", " world"},
+			o200k:  []string{"Hello", " world"},
+		},
+		{
+			name:   "camel case",
+			text:   "parseHTTPRequest",
+			cl100k: []string{"parseHTTPRequest"},
+			o200k:  []string{"parse", "HTTPRequest"},
+		},
+		{
+			name:   "contractions",
+			text:   "I'm sure they'LL",
+			cl100k: []string{"I", "'m", " sure", " they", "'LL"},
+			o200k:  []string{"I'm", " sure", " they'LL"},
+		},
+		{
+			name:   "digits in groups of three",
+			text:   "12345",
+			cl100k: []string{"123", "45"},
+			o200k:  []string{"123", "45"},
+		},
+		{
+			name:   "punctuation and newlines",
+			text:   "x := y{}\n\n\tz",
+			cl100k: []string{"x", " :=", " y", "{}\n\n", "\tz"},
+			o200k:  []string{"x", " :=", " y", "{}\n\n", "\tz"},
+		},
+		{
+			name:   "slashes",
+			text:   "a //\n/b",
+			cl100k: []string{"a", " //\n", "/b"},
+			o200k:  []string{"a", " //\n/", "b"},
+		},
+		{
+			name:   "whitespace",
+			text:   "a   b  \n c  ",
+			cl100k: []string{"a", "  ", " b", "  \n", " c", "  "},
+			o200k:  []string{"a", "  ", " b", "  \n", " c", "  "},
+		},
+		{
+			name:   "non-ASCII letters",
+			text:   "Größe über",
+			cl100k: []string{"Größe", " über"},
+			o200k:  []string{"Größe", " über"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.cl100k, splitPieces(tc.text, matchCL100k), "cl100k")
+			assert.Equal(t, tc.o200k, splitPieces(tc.text, matchO200k), "o200k")
+		})
+	}
+}
+
+// fixtureVocab builds a tiktoken rank file from tokens listed in rank order.
+func fixtureVocab(tokens ...string) string {
+	var sb strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	return sb.String()
+}
+
+func TestBPECount(t *testing.T) {
+	vocab := fixtureVocab("a", "b", "c", "d", " ", "bc", "ab", "cd", "abcd", " a")
+	bpe, err := NewBPE("cl100k", strings.NewReader(vocab))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		text  string
+		count int
+	}{
+		// A piece in the vocabulary is one token.
+		{"abcd", 1},
+		// The lowest-ranked pair merges first: "bc" before "ab" and "cd", after which
+		// neither "abc" nor "bcd" is in the vocabulary.
+		{"abc", 2},
+		{"bcd", 2},
+		{"abcda", 4},
+		{" abcd", 3},
+		// Merges do not cross piece boundaries: "abcd" and " abcd" are encoded apart.
+		{"abcd abcd", 4},
+		{"dcba", 4},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.count, bpe.Count(tc.text), "Count(%q)", tc.text)
+	}
+}
+
+// TestBPEGolden checks counts against the output of OpenAI's tiktoken. It needs the
+// real vocabularies, from go generate or $CTXCAT_TOKENIZER_DIR.
+func TestBPEGolden(t *testing.T) {
+	testCases := []struct {
+		vocab string
+		text  string
+		count int
+	}{
+		{"cl100k", "hello world", 2},
+		{"cl100k", "tiktoken is great!", 6},
+		{"cl100k", "2 + 2 = 4", 7},
+		{"cl100k", "antidisestablishmentarianism", 6},
+		{"o200k", "hello world", 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.vocab+"/"+tc.text, func(t *testing.T) {
+			bpe, err := LoadBPE(tc.vocab)
+			if err != nil {
+				t.Skipf("vocabulary not available: %v", err)
+			}
+			assert.Equal(t, tc.count, bpe.Count(tc.text))
+		})
+	}
+}
+
+func TestNewBPEErrors(t *testing.T) {
+	_, err := NewBPE("cl100k", strings.NewReader(""))
+	assert.ErrorContains(t, err, "is empty")
+	_, err = NewBPE("cl100k", strings.NewReader("YQ== x\n"))
+	assert.ErrorContains(t, err, "line 1")
+	_, err = NewBPE("p50k", strings.NewReader(fixtureVocab("a")))
+	assert.ErrorContains(t, err, "no pre-tokenizer")
+}
@@ -0,0 +1,19 @@
+package tokenizer
+
+import "unicode/utf8"
+
+// Estimator approximates token counts as one token per four characters.
+// It is fast and dependency-free, and is usually within 10-20% of real BPE
+// counts for source code and English prose.
+type Estimator struct{}
+
+// Name implements Tokenizer.
+func (Estimator) Name() string {
+	return DefaultName
+}
+
+// Count implements Tokenizer.
+func (Estimator) Count(text string) int {
+	n := utf8.RuneCountInString(text)
+	return (n + 3) / 4
+}
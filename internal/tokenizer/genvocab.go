@@ -0,0 +1,70 @@
+//go:build ignore
+
+// genvocab downloads the tiktoken vocabularies into vocab/, where they are embedded
+// into the binary, and checks them against their published SHA-256 digests.
+// Run it with "go generate ./internal/tokenizer".
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var vocabularies = []struct {
+	name, url, digest string
+}{
+	{
+		name:   "cl100k_base.tiktoken",
+		url:    "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		digest: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcf1a1a8e3f4",
+	},
+	{
+		name:   "o200k_base.tiktoken",
+		url:    "https://openaipublic.blob.core.windows.net/encodings/o200k_base.tiktoken",
+		digest: "446a9538cb6c348e3516120d7c08b09f57c36495e2acfffe59a5bf8b0cfb1a2d",
+	},
+}
+
+func main() {
+	for _, v := range vocabularies {
+		path := filepath.Join("vocab", v.name)
+		if data, err := os.ReadFile(path); err == nil && digest(data) == v.digest {
+			continue
+		}
+		data, err := download(v.url)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "genvocab: %v\n", err)
+			os.Exit(1)
+		}
+		if got := digest(data); got != v.digest {
+			fmt.Fprintf(os.Stderr, "genvocab: %s has SHA-256 %s, expected %s\n", v.url, got, v.digest)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "genvocab: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("downloading %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
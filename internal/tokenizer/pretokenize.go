@@ -0,0 +1,225 @@
+package tokenizer
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A pretokenizer returns the byte length of the piece at the start of a non-empty
+// text. Byte-pair merges never cross the boundaries between pieces.
+type pretokenizer func(s string) int
+
+// pretokenizers are the pre-tokenizers of the vocabularies, by name.
+var pretokenizers = map[string]pretokenizer{
+	"cl100k": matchCL100k,
+	"o200k":  matchO200k,
+}
+
+// splitPieces splits text into the pieces matched by match.
+func splitPieces(text string, match pretokenizer) []string {
+	var pieces []string
+	for len(text) > 0 {
+		n := match(text)
+		pieces = append(pieces, text[:n])
+		text = text[n:]
+	}
+	return pieces
+}
+
+// matchCL100k matches a piece the way the cl100k regular expression does:
+//
+//	(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}|
+//	 ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Go's regexp package has no lookahead, so the alternatives are matched by hand.
+func matchCL100k(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	// Contractions.
+	if r == '\'' {
+		if n := contraction(s); n > 0 {
+			return n
+		}
+	}
+
+	// An optional non-letter, non-digit prefix followed by letters.
+	if unicode.IsLetter(r) {
+		return size + spanOf(s[size:], unicode.IsLetter)
+	}
+	if r != '\r' && r != '\n' && !unicode.IsNumber(r) {
+		if letters := spanOf(s[size:], unicode.IsLetter); letters > 0 {
+			return size + letters
+		}
+	}
+
+	if n := matchDigits(s); n > 0 {
+		return n
+	}
+	if n := matchPunct(s, isNewline); n > 0 {
+		return n
+	}
+	return matchSpace(s)
+}
+
+// matchO200k matches a piece the way the o200k regular expression does:
+//
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?|
+//	\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+|\s+(?!\S)|\s+
+//
+// Unlike cl100k, words are split before upper-case letters that follow lower-case
+// ones, as in "camel|Case", and contractions stay attached to their word.
+func matchO200k(s string) int {
+	r, size := utf8.DecodeRuneInString(s)
+
+	// Each word alternative is tried with the optional prefix, then without it.
+	starts := []int{0}
+	if r != '\r' && r != '\n' && !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+		starts = []int{size, 0}
+	}
+	for _, word := range []func(string) int{lowerWord, upperWord} {
+		for _, start := range starts {
+			if n := word(s[start:]); n > 0 {
+				return start + n + contraction(s[start+n:])
+			}
+		}
+	}
+
+	if n := matchDigits(s); n > 0 {
+		return n
+	}
+	if n := matchPunct(s, func(r rune) bool { return isNewline(r) || r == '/' }); n > 0 {
+		return n
+	}
+	return matchSpace(s)
+}
+
+// lowerWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+,
+// returning 0 if s does not start with it.
+func lowerWord(s string) int {
+	upper := spanOf(s, isUpperLetter)
+	// The upper-case run backtracks until the lower-case run can start.
+	for start := upper; start >= 0; {
+		if lower := spanOf(s[start:], isLowerLetter); lower > 0 {
+			return start + lower
+		}
+		if start == 0 {
+			break
+		}
+		_, size := utf8.DecodeLastRuneInString(s[:start])
+		start -= size
+	}
+	return 0
+}
+
+// upperWord matches [\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*,
+// returning 0 if s does not start with it.
+func upperWord(s string) int {
+	upper := spanOf(s, isUpperLetter)
+	if upper == 0 {
+		return 0
+	}
+	return upper + spanOf(s[upper:], isLowerLetter)
+}
+
+func isUpperLetter(r rune) bool {
+	return unicode.In(r, unicode.Lu, unicode.Lt, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+func isLowerLetter(r rune) bool {
+	return unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.M)
+}
+
+// contraction returns the length of the contraction at the start of s, matching
+// (?i:'s|'t|'re|'ve|'m|'ll|'d), or 0.
+func contraction(s string) int {
+	if len(s) == 0 || s[0] != '\'' {
+		return 0
+	}
+	rest := s[1:]
+	for _, suffix := range []string{"s", "t", "re", "ve", "m", "ll", "d"} {
+		if len(rest) >= len(suffix) && equalFoldASCII(rest[:len(suffix)], suffix) {
+			return 1 + len(suffix)
+		}
+	}
+	return 0
+}
+
+// matchDigits matches \p{N}{1,3}.
+func matchDigits(s string) int {
+	n, count := 0, 0
+	for n < len(s) && count < 3 {
+		d, size := utf8.DecodeRuneInString(s[n:])
+		if !unicode.IsNumber(d) {
+			break
+		}
+		n += size
+		count++
+	}
+	return n
+}
+
+// matchPunct matches punctuation, optionally preceded by a single space and
+// followed by the runes accepted by trailing: " ?[^\s\p{L}\p{N}]+[trailing]*".
+func matchPunct(s string, trailing func(rune) bool) int {
+	start := 0
+	if len(s) > 0 && s[0] == ' ' {
+		start = 1
+	}
+	punct := spanOf(s[start:], isPunct)
+	if punct == 0 {
+		return 0
+	}
+	end := start + punct
+	return end + spanOf(s[end:], trailing)
+}
+
+// matchSpace matches whitespace: \s*[\r\n]+|\s+(?!\S)|\s+. Anything else is a piece
+// of one rune.
+func matchSpace(s string) int {
+	ws := spanOf(s, unicode.IsSpace)
+	if ws == 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		return size
+	}
+	for i := ws - 1; i >= 0; i-- {
+		if s[i] == '\r' || s[i] == '\n' {
+			return i + 1
+		}
+	}
+	if ws == len(s) {
+		return ws
+	}
+	// The last space goes with the word that follows.
+	_, lastSize := utf8.DecodeLastRuneInString(s[:ws])
+	if ws > lastSize {
+		return ws - lastSize
+	}
+	return ws
+}
+
+// spanOf returns the byte length of the prefix of s whose runes all satisfy pred.
+func spanOf(s string, pred func(rune) bool) int {
+	n := 0
+	for n < len(s) {
+		r, size := utf8.DecodeRuneInString(s[n:])
+		if !pred(r) {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+func isPunct(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func isNewline(r rune) bool {
+	return r == '\r' || r == '\n'
+}
+
+func equalFoldASCII(a, b string) bool {
+	return bytes.EqualFold([]byte(a), []byte(b))
+}
@@ -0,0 +1,32 @@
+package tokenizer
+
+import (
+	"fmt"
+)
+
+// Tokenizer counts the number of tokens a piece of text will occupy in a model's context.
+type Tokenizer interface {
+	// Name returns the identifier used to select this tokenizer on the command line.
+	Name() string
+	// Count returns the number of tokens in text.
+	Count(text string) int
+}
+
+// DefaultName is the tokenizer used when none is requested.
+const DefaultName = "estimate"
+
+// New returns the tokenizer registered under name.
+// "estimate" is a cheap character-based heuristic; "cl100k" and "o200k" are exact
+// byte-pair encoders whose vocabularies are loaded by LoadBPE.
+func New(name string) (Tokenizer, error) {
+	switch name {
+	case "", DefaultName:
+		return Estimator{}, nil
+	case "cl100k", "cl100k_base":
+		return LoadBPE("cl100k")
+	case "o200k", "o200k_base":
+		return LoadBPE("o200k")
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q (expected estimate, cl100k or o200k)", name)
+	}
+}
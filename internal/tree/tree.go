@@ -0,0 +1,84 @@
+package tree
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Entry is a path to draw in the tree, with an optional note shown after its name.
+type Entry struct {
+	Path  string
+	IsDir bool
+	Note  string
+}
+
+// node is a directory or file in the rendered tree.
+type node struct {
+	name     string
+	isDir    bool
+	note     string
+	children map[string]*node
+}
+
+func (n *node) child(name string) *node {
+	if n.children == nil {
+		n.children = make(map[string]*node)
+	}
+	c, ok := n.children[name]
+	if !ok {
+		c = &node{name: name}
+		n.children[name] = c
+	}
+	return c
+}
+
+// RenderEntries draws an ASCII tree of the given entries, rooted at ".".
+// Directories are shown with a trailing slash.
+func RenderEntries(entries []Entry) string {
+	root := &node{name: ".", isDir: true}
+	for _, entry := range entries {
+		current := root
+		for _, part := range strings.Split(filepath.ToSlash(filepath.Clean(entry.Path)), "/") {
+			if part == "" || part == "." {
+				continue
+			}
+			current.isDir = true
+			current = current.child(part)
+		}
+		if current != root {
+			current.isDir = current.isDir || entry.IsDir
+			current.note = entry.Note
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(root.name + "\n")
+	writeChildren(&sb, root, "")
+	return sb.String()
+}
+
+func writeChildren(sb *strings.Builder, n *node, prefix string) {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		connector, childPrefix := "├── ", "│   "
+		if i == len(names)-1 {
+			connector, childPrefix = "└── ", "    "
+		}
+		c := n.children[name]
+		label := c.name
+		if c.isDir {
+			label += "/"
+		}
+		if c.note != "" {
+			label += " (" + c.note + ")"
+		}
+		sb.WriteString(prefix + connector + label + "\n")
+		writeChildren(sb, c, prefix+childPrefix)
+	}
+}
@@ -8,7 +8,8 @@ import (
 )
 
 // GetInputPaths returns a list of paths from command line arguments or stdin.
-func GetInputPaths(args []string) ([]string, error) {
+// defaultPaths are used when there are no arguments and nothing was piped in.
+func GetInputPaths(args []string, defaultPaths []string) ([]string, error) {
 	if len(args) > 0 {
 		return args, nil
 	}
@@ -27,9 +28,16 @@ func GetInputPaths(args []string) ([]string, error) {
 		if err := scanner.Err(); err != nil {
 			return nil, fmt.Errorf("error reading from stdin: %w", err)
 		}
+		if len(paths) == 0 && len(defaultPaths) > 0 {
+			return defaultPaths, nil
+		}
 		return paths, nil
 	}
 
+	if len(defaultPaths) > 0 {
+		return defaultPaths, nil
+	}
+
 	// No args and no pipe, default to current directory
 	return []string{"."}, nil
 }